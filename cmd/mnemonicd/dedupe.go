@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/migrations"
+)
+
+const dedupeUsage = `usage: mnemonicd dedupe [-dry-run]
+
+Merge bookmarks of the same page, going by the URL rules in config.json.
+Each group of duplicates is merged into its oldest bookmark, which gains
+the tags and notes of the others.
+
+  -dry-run  list the duplicates without merging them
+`
+
+func dedupe(conf *config.Config, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, dedupeUsage) }
+	dryRun := flags.Bool("dry-run", false, "")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	_, err := migrations.NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store := bookmark.NewSQLiteBookmarkStore(db, *conf.Tags, *conf.URLs)
+	duplicates, err := store.Dedupe(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	verb := "merged"
+	if *dryRun {
+		verb = "would merge"
+	}
+
+	merged := 0
+	for _, d := range duplicates {
+		fmt.Printf("%d %s\n", d.Kept.ID, d.Kept.URL)
+		for _, b := range d.Merged {
+			fmt.Printf("  %s %d %s\n", verb, b.ID, b.URL)
+			merged++
+		}
+	}
+
+	fmt.Printf("%s %d bookmarks into %d\n", verb, merged, len(duplicates))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/cmessinides/mnemonic/internal/backup"
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/exporter"
+	"github.com/cmessinides/mnemonic/internal/migrations"
+)
+
+const exportUsage = `usage: mnemonicd export [-format netscape|jsonl] [-tag TAG] [-archived true|false] [-o FILE]
+
+Export bookmarks to standard output or a file.
+
+  -format    the file format; netscape (the default) writes the bookmarks.html
+             format that web browsers import, while jsonl writes a backup of
+             every bookmark and tag alias that import -format jsonl restores
+  -tag       only export bookmarks with this tag or a tag beneath it
+  -archived  only export archived (true) or active (false) bookmarks
+  -o         write to FILE instead of standard output
+`
+
+func exportBookmarks(conf *config.Config, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, exportUsage) }
+	format := flags.String("format", "netscape", "")
+	tagFlag := flags.String("tag", "", "")
+	archivedFlag := flags.String("archived", "", "")
+	out := flags.String("o", "", "")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 || (*format != "netscape" && *format != "jsonl") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "jsonl" && (*tagFlag != "" || *archivedFlag != "") {
+		log.Fatalln("-tag and -archived can't be used with -format jsonl, which backs up everything")
+	}
+
+	filter := bookmark.Filter{State: bookmark.StateAll}
+	if *tagFlag != "" {
+		filter.Tags = []string{*tagFlag}
+	}
+	if *archivedFlag != "" {
+		archived, err := strconv.ParseBool(*archivedFlag)
+		if err != nil {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		filter.State = bookmark.StateActive
+		if archived {
+			filter.State = bookmark.StateArchived
+		}
+	}
+
+	_, err := migrations.NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "jsonl" {
+		_, err = backup.WriteJSONL(db, w)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	store := bookmark.NewSQLiteBookmarkStore(db, *conf.Tags, *conf.URLs)
+	err = exporter.ExportNetscape(context.Background(), w, store, filter)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
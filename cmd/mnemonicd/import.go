@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cmessinides/mnemonic/internal/backup"
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/importer"
+	"github.com/cmessinides/mnemonic/internal/migrations"
+)
+
+const importUsage = `usage: mnemonicd import [-format netscape|pinboard|pocket|jsonl] [-duplicates skip|merge|overwrite] [-dry-run] FILE
+
+Import bookmarks exported from another application, or restore a backup.
+
+  -format      the format of FILE: netscape (the default) for the
+               bookmarks.html files exported by web browsers, where folders
+               become tags; pinboard for a Pinboard JSON export; or pocket
+               for a Pocket CSV or HTML export; or jsonl to restore a backup
+               written by export -format jsonl into an empty database
+  -duplicates  what to do with bookmarks that already exist: skip them
+               (default), merge their tags, or overwrite them
+  -dry-run     print what would change without changing anything
+`
+
+func importBookmarks(conf *config.Config, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, importUsage) }
+	formatFlag := flags.String("format", string(importer.FormatNetscape), "")
+	duplicatesFlag := flags.String("duplicates", string(importer.DuplicateSkip), "")
+	dryRun := flags.Bool("dry-run", false, "")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *formatFlag == "jsonl" {
+		if *dryRun {
+			log.Fatalln("-dry-run can't be used with -format jsonl")
+		}
+
+		restore(db, flags.Arg(0))
+		return
+	}
+
+	format, err := importer.ParseFormat(*formatFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	opts := importer.Options{DryRun: *dryRun, TagRules: *conf.Tags, URLRules: *conf.URLs}
+	opts.Duplicates, err = importer.ParseDuplicatePolicy(*duplicatesFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+
+	items, err := importer.Parse(format, f)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err = migrations.NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store := bookmark.NewSQLiteBookmarkStore(db, *conf.Tags, *conf.URLs)
+	_, err = store.UpdateURLKeys(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	report, err := importer.NewImporter(store, opts).Import(context.Background(), items)
+	if report != nil {
+		printReport(report)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// restore loads a JSONL backup into an empty database.
+func restore(db *sql.DB, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+
+	_, err = migrations.NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	stats, err := backup.RestoreJSONL(db, f)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("restored %d bookmarks and %d tag aliases\n", stats.Bookmarks, stats.Aliases)
+}
+
+func printReport(r *importer.Report) {
+	for _, c := range r.Changes {
+		archived := ""
+		if c.Archived {
+			archived = " archived"
+		}
+		fmt.Printf("%-9s  %s %q%s [%s]\n", c.Action+":", c.URL, c.Title, archived, strings.Join(c.Tags, ", "))
+	}
+
+	if !r.DryRun {
+		for _, d := range r.Duplicates {
+			fmt.Printf("duplicate: %s (%s)\n", d.URL, d.Action)
+		}
+	}
+
+	for _, e := range r.Errors {
+		fmt.Printf("failed:    %s (%s)\n", e.URL, e.Error)
+	}
+
+	verb := ""
+	if r.DryRun {
+		verb = "would be "
+	}
+	fmt.Printf("%d %screated, %d skipped, %d merged, %d overwritten, %d failed\n",
+		r.Created, verb, r.Skipped, r.Merged, r.Overwritten, r.Failed)
+}
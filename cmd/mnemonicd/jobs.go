@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/backup"
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/jobs"
+	"github.com/cmessinides/mnemonic/internal/linkcheck"
+	"github.com/cmessinides/mnemonic/internal/media"
+)
+
+// newJobRunner returns a runner for the server's background jobs, with each
+// recurring job scheduled as the config says.
+func newJobRunner(conf *config.Config, queue jobs.Store, backups *backup.Manager, worker *media.Worker, checker *linkcheck.Checker) *jobs.Runner {
+	runner := jobs.NewRunner(queue, jobs.Options{
+		Workers:      conf.Jobs.Workers,
+		PollInterval: time.Duration(conf.Jobs.PollInterval),
+		Backoff:      time.Duration(conf.Jobs.Backoff),
+		MaxBackoff:   time.Duration(conf.Jobs.MaxBackoff),
+		KeepDone:     time.Duration(conf.Jobs.KeepDone),
+	})
+
+	runner.Handle("backup", func(ctx context.Context, job *jobs.Job) error {
+		f, err := backups.Backup()
+		if err != nil {
+			return err
+		}
+
+		log.Printf("backed up database to %s", f.Path)
+		return nil
+	})
+	recur(runner, conf, "backup", conf.Backup.Interval)
+
+	runner.Handle("media", func(ctx context.Context, job *jobs.Job) error {
+		n, err := worker.Run(ctx)
+		if n > 0 {
+			log.Printf("checked %d bookmarks for icons and previews", n)
+		}
+		return err
+	})
+	recur(runner, conf, "media", conf.Media.Interval)
+
+	runner.Handle("linkcheck", func(ctx context.Context, job *jobs.Job) error {
+		n, err := checker.Run(ctx)
+		if n > 0 {
+			log.Printf("checked the links of %d bookmarks", n)
+		}
+		return err
+	})
+	recur(runner, conf, "linkcheck", conf.LinkCheck.Interval)
+
+	return runner
+}
+
+// recur schedules a job by its entry in jobs.schedules, or else every
+// interval. A job with neither only runs when it is retried by hand.
+func recur(runner *jobs.Runner, conf *config.Config, kind string, interval config.Duration) {
+	if spec, ok := conf.Jobs.Schedules[kind]; ok {
+		// the schedules were checked when the config was read
+		schedule, err := jobs.ParseSchedule(spec)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		runner.Recur(kind, schedule)
+		return
+	}
+
+	if interval > 0 {
+		runner.Recur(kind, jobs.Every(time.Duration(interval)))
+	}
+}
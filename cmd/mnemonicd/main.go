@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/cmessinides/mnemonic/internal/backup"
 	"github.com/cmessinides/mnemonic/internal/bookmark"
 	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/jobs"
+	"github.com/cmessinides/mnemonic/internal/linkcheck"
+	"github.com/cmessinides/mnemonic/internal/media"
+	"github.com/cmessinides/mnemonic/internal/metadata"
+	"github.com/cmessinides/mnemonic/internal/migrations"
 	"github.com/cmessinides/mnemonic/internal/server"
+	"github.com/cmessinides/mnemonic/internal/tag"
 	_ "modernc.org/sqlite"
 )
 
 var DevMode = "off"
 
+const usage = `usage: mnemonicd [command]
+
+commands:
+  serve                   start the server (default)
+  migrate status|up|down  inspect or change the database schema version
+  tags normalize          apply the tag rules to existing tags
+  dedupe                  merge bookmarks of the same page
+  import FILE             import bookmarks exported from a browser or service
+  export                  export bookmarks for a browser to import
+  restore FILE            replace the database with a backup
+`
+
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -44,19 +66,103 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	db.SetMaxOpenConns(conf.Database.MaxOpenConns)
+
+	cmd := "serve"
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+
+	switch cmd {
+	case "serve":
+		serve(conf, db)
+	case "migrate":
+		migrate(db, os.Args[2:])
+	case "tags":
+		tags(conf, db, os.Args[2:])
+	case "dedupe":
+		dedupe(conf, db, os.Args[2:])
+	case "import":
+		importBookmarks(conf, db, os.Args[2:])
+	case "export":
+		exportBookmarks(conf, db, os.Args[2:])
+	case "restore":
+		restoreBackup(conf, db, os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
 
-	bookmarks := bookmark.NewSQLiteBookmarkStore(db)
-	err = bookmarks.Init()
+func serve(conf *config.Config, db *sql.DB) {
+	_, err := migrations.NewMigrator(db).Up()
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	bookmarks := bookmark.NewSQLiteBookmarkStore(db, *conf.Tags, *conf.URLs)
+	tags := tag.NewSQLiteTagStore(db, *conf.Tags)
+	backups := newBackupManager(conf, db)
+
+	// duplicates are found by URL keys, which change with the URL rules
+	n, err := bookmarks.UpdateURLKeys(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	} else if n > 0 {
+		log.Printf("updated the URL keys of %d bookmarks; run mnemonicd dedupe to merge any duplicates", n)
+	}
+
+	fetcher := newFetcher(conf)
+	cache := media.NewCache(conf.MediaDir())
+	worker := media.NewWorker(bookmarks, fetcher, cache, nil, media.Options{
+		IconSize:      conf.Media.IconSize,
+		PreviewWidth:  conf.Media.PreviewWidth,
+		PreviewHeight: conf.Media.PreviewHeight,
+		MaxBytes:      conf.Media.MaxBytes,
+		Timeout:       time.Duration(conf.Media.Timeout),
+		UserAgent:     conf.Metadata.UserAgent,
+	})
+
+	checks := linkcheck.NewSQLiteStore(db)
+	checker := linkcheck.NewChecker(checks, nil, linkcheck.Options{
+		Recheck:     time.Duration(conf.LinkCheck.Recheck),
+		HostDelay:   time.Duration(conf.LinkCheck.HostDelay),
+		Timeout:     time.Duration(conf.LinkCheck.Timeout),
+		Workers:     conf.LinkCheck.Workers,
+		BrokenAfter: conf.LinkCheck.BrokenAfter,
+		UserAgent:   conf.Metadata.UserAgent,
+		URLRules:    *conf.URLs,
+	})
+
+	queue := jobs.NewSQLiteStore(db)
+	runner := newJobRunner(conf, queue, backups, worker, checker)
+	go runner.Run(context.Background())
+
 	s := server.NewServer(&server.Config{
 		// go run -ldflags "-X main.DevMode=on" ./cmd/mnemonicd
 		ServerConfig: *conf.Server,
 		Dev:          DevMode == "on",
 		LookupEnv:    os.LookupEnv,
-	}, bookmarks)
+		TagRules:     *conf.Tags,
+		URLRules:     *conf.URLs,
+	}, bookmarks, tags, backups, fetcher, cache, checks, queue)
 
 	s.Start()
 }
+
+func newFetcher(conf *config.Config) *metadata.Fetcher {
+	return metadata.NewFetcher(nil, metadata.Options{
+		Timeout:   time.Duration(conf.Metadata.Timeout),
+		MaxBytes:  conf.Metadata.MaxBytes,
+		UserAgent: conf.Metadata.UserAgent,
+	})
+}
+
+func newBackupManager(conf *config.Config, db *sql.DB) *backup.Manager {
+	return backup.NewManager(db, conf.BackupDir(), backup.Retention{
+		Daily:  conf.Backup.KeepDaily,
+		Weekly: conf.Backup.KeepWeekly,
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cmessinides/mnemonic/internal/migrations"
+)
+
+const migrateUsage = `usage: mnemonicd migrate status|up|down
+
+  status  print the current and latest schema versions
+  up      apply all pending migrations
+  down    revert the most recently applied migration
+`
+
+func migrate(db *sql.DB, args []string) {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	m := migrations.NewMigrator(db)
+
+	switch args[0] {
+	case "status":
+		s, err := m.Status()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		fmt.Printf("current version: %d\nlatest version:  %d\n", s.Current, s.Latest)
+		if s.Current > s.Latest {
+			fmt.Println("the database is newer than this build of mnemonic")
+		}
+		for _, mig := range s.Pending {
+			fmt.Printf("pending: %04d %s\n", mig.Version, mig.Name)
+		}
+	case "up":
+		applied, err := m.Up()
+		for _, mig := range applied {
+			fmt.Printf("applied: %04d %s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("already up to date")
+		}
+	case "down":
+		mig, err := m.Down()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if mig == nil {
+			fmt.Println("no migrations to revert")
+		} else {
+			fmt.Printf("reverted: %04d %s\n", mig.Version, mig.Name)
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
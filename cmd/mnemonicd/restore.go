@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cmessinides/mnemonic/internal/backup"
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/migrations"
+)
+
+const restoreUsage = `usage: mnemonicd restore FILE
+
+Replace the database with a backup, such as one from the backups directory.
+The backup's integrity is checked first, and the current database is saved
+to the backups directory before it is replaced. Stop the server before
+restoring.
+`
+
+func restoreBackup(conf *config.Config, db *sql.DB, args []string) {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, restoreUsage)
+		os.Exit(2)
+	}
+	path := args[0]
+
+	version, err := backup.Verify(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	latest := migrations.NewMigrator(db).Latest()
+	if version > latest {
+		log.Fatalln(&migrations.TooNewError{Version: version, Latest: latest})
+	}
+
+	current, err := newBackupManager(conf, db).Snapshot("pre-restore")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("saved the current database to %s\n", current.Path)
+
+	err = db.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = backup.Replace(conf.DBPath(), path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("restored %s (schema version %d)\n", path, version)
+	if version < latest {
+		fmt.Println("the restored database will be migrated to the latest version when the server starts")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/migrations"
+	"github.com/cmessinides/mnemonic/internal/tag"
+)
+
+const tagsUsage = `usage: mnemonicd tags normalize
+
+  normalize  rename existing tags to follow the tag rules in config.json,
+             merging tags that end up with the same name
+`
+
+func tags(conf *config.Config, db *sql.DB, args []string) {
+	if len(args) != 1 || args[0] != "normalize" {
+		fmt.Fprint(os.Stderr, tagsUsage)
+		os.Exit(2)
+	}
+
+	_, err := migrations.NewMigrator(db).Up()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	results, err := tag.NewSQLiteTagStore(db, *conf.Tags).NormalizeAll(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var renamed, merged, invalid int
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			invalid++
+			fmt.Printf("skipped: %q (%s)\n", r.Name, r.Err.(*tag.InvalidError).Reason)
+		case r.Merged:
+			merged++
+			fmt.Printf("merged:  %q into %q\n", r.Name, r.NewName)
+		default:
+			renamed++
+			fmt.Printf("renamed: %q to %q\n", r.Name, r.NewName)
+		}
+	}
+
+	fmt.Printf("%d renamed, %d merged, %d skipped\n", renamed, merged, invalid)
+}
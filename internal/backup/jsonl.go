@@ -0,0 +1,238 @@
+// Package backup writes the whole database to a portable file and restores
+// it again.
+package backup
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/metadata"
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNotEmpty is returned when restoring into a database that already has
+// bookmarks or tags, which a restore could conflict with.
+var ErrNotEmpty = errors.New("the database is not empty; restore into a new database")
+
+const (
+	RecordBookmark = "bookmark"
+	RecordAlias    = "alias"
+)
+
+// Record is one line of a JSONL backup. Type says which of the other fields
+// are set: bookmarks have every field but Alias and Tag, while aliases only
+// have Alias and Tag.
+type Record struct {
+	Type        string             `json:"type"`
+	ID          int64              `json:"id,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	URL         string             `json:"url,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Notes       string             `json:"notes,omitempty"`
+	Metadata    *metadata.Metadata `json:"metadata,omitempty"`
+	Tags        tag.Tags           `json:"tags,omitempty"`
+	CreatedAt   *time.Time         `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time         `json:"updatedAt,omitempty"`
+	ArchivedAt  *time.Time         `json:"archivedAt,omitempty"`
+	Alias       string             `json:"alias,omitempty"`
+	Tag         string             `json:"tag,omitempty"`
+}
+
+// Stats counts the records written or restored.
+type Stats struct {
+	Bookmarks int
+	Aliases   int
+}
+
+// WriteJSONL writes every bookmark, archived or not, followed by every tag
+// alias, as one JSON record per line. Rows are streamed from the database
+// rather than loaded all at once.
+func WriteJSONL(db *sql.DB, w io.Writer) (*Stats, error) {
+	dbx := sqlx.NewDb(db, "sqlite")
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	stats := &Stats{}
+
+	rows, err := dbx.Queryx(`
+        SELECT a.id, a.title, a.url, a.description, a.notes, a.metadata, a.tags, a.created_at, a.updated_at, b.archived_at
+        FROM all_bookmarks a
+        JOIN bookmarks b ON b.id = a.id
+        ORDER BY a.id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := &Record{Type: RecordBookmark}
+		err = rows.Scan(&r.ID, &r.Title, &r.URL, &r.Description, &r.Notes, &r.Metadata, &r.Tags, &r.CreatedAt, &r.UpdatedAt, &r.ArchivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not read bookmark: %w", err)
+		}
+
+		err = enc.Encode(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not write bookmark: %w", err)
+		}
+		stats.Bookmarks++
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmarks: %w", err)
+	}
+
+	aliases, err := dbx.Queryx(`
+        SELECT a.alias, t.name
+        FROM tag_aliases a
+        JOIN tags t ON t.id = a.tag_id
+        ORDER BY a.alias
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("could not select tag aliases: %w", err)
+	}
+	defer aliases.Close()
+
+	for aliases.Next() {
+		r := &Record{Type: RecordAlias}
+		err = aliases.Scan(&r.Alias, &r.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tag alias: %w", err)
+		}
+
+		err = enc.Encode(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not write tag alias: %w", err)
+		}
+		stats.Aliases++
+	}
+
+	err = aliases.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not select tag aliases: %w", err)
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RestoreJSONL restores a backup written by WriteJSONL into an empty
+// database, keeping bookmark IDs, timestamps, and tag names exactly as they
+// were. Records are read one at a time and the restore happens in a single
+// transaction, so a failed restore leaves the database empty.
+func RestoreJSONL(db *sql.DB, r io.Reader) (*Stats, error) {
+	tx, err := sqlx.NewDb(db, "sqlite").Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	defer tx.Rollback()
+
+	var used bool
+	err = tx.Get(&used, "SELECT EXISTS (SELECT 1 FROM bookmarks) OR EXISTS (SELECT 1 FROM tags)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	if used {
+		return nil, ErrNotEmpty
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	stats := &Stats{}
+	for line := 1; ; line++ {
+		rec := &Record{}
+		err = dec.Decode(rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read record %d: %w", line, err)
+		}
+
+		switch rec.Type {
+		case RecordBookmark:
+			err = restoreBookmark(tx, rec)
+			stats.Bookmarks++
+		case RecordAlias:
+			err = restoreAlias(tx, rec)
+			stats.Aliases++
+		default:
+			err = fmt.Errorf("unknown record type %q", rec.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not restore record %d: %w", line, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return stats, nil
+}
+
+func restoreBookmark(tx *sqlx.Tx, rec *Record) error {
+	if rec.ID == 0 || rec.URL == "" || rec.CreatedAt == nil || rec.UpdatedAt == nil {
+		return errors.New("bookmark is missing an id, url, or timestamp")
+	}
+
+	_, err := tx.Exec(`
+        INSERT INTO bookmarks (id, title, url, description, notes, metadata, created_at, updated_at, archived_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, rec.ID, bookmark.StripControl(rec.Title), rec.URL, bookmark.StripControl(rec.Description), bookmark.StripControl(rec.Notes), rec.Metadata, rec.CreatedAt, rec.UpdatedAt, rec.ArchivedAt)
+	if err != nil {
+		return err
+	}
+
+	if len(rec.Tags) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO tags (name)
+        SELECT DISTINCT value FROM json_each(?) WHERE true
+        ON CONFLICT (name) DO NOTHING
+    `, rec.Tags)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id)
+        SELECT ?, t.id FROM tags t, json_each(?) j WHERE t.name = j.value
+    `, rec.ID, rec.Tags)
+
+	return err
+}
+
+func restoreAlias(tx *sqlx.Tx, rec *Record) error {
+	if rec.Alias == "" || rec.Tag == "" {
+		return errors.New("alias is missing an alias or tag")
+	}
+
+	var id int64
+	err := tx.Get(&id, `
+        INSERT INTO tags (name) VALUES (?)
+        ON CONFLICT (name) DO UPDATE SET name = excluded.name
+        RETURNING id
+    `, rec.Tag)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO tag_aliases (alias, tag_id) VALUES (?, ?)", rec.Alias, id)
+
+	return err
+}
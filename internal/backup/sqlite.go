@@ -0,0 +1,300 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix = "mnemonic-"
+	fileSuffix = ".sqlite"
+	fileTime   = "20060102T150405Z"
+)
+
+// File is a backup of the database in the backup directory.
+type File struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Retention decides which backups are kept after a new one is made. The
+// latest backup of each of the last Daily days and of each of the last
+// Weekly weeks are kept, and the rest are deleted.
+type Retention struct {
+	Daily  int
+	Weekly int
+}
+
+// Manager makes consistent copies of a live database with VACUUM INTO, so
+// the server doesn't have to stop while it is backed up.
+type Manager struct {
+	db        *sql.DB
+	dir       string
+	retention Retention
+	mu        sync.Mutex
+}
+
+func NewManager(db *sql.DB, dir string, retention Retention) *Manager {
+	return &Manager{
+		db:        db,
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Backup copies the database to a new file in the backup directory, then
+// deletes old backups according to the retention policy.
+func (m *Manager) Backup() (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := m.vacuumInto("")
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.prune()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Snapshot copies the database to a new file in the backup directory with
+// label in its name, like "mnemonic-label-20250102T150405Z.sqlite".
+// Snapshots aren't listed or deleted by the retention policy.
+func (m *Manager) Snapshot(label string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.vacuumInto(label)
+}
+
+func (m *Manager) vacuumInto(label string) (*File, error) {
+	err := os.MkdirAll(m.dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create backup directory: %w", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	name := filePrefix + now.Format(fileTime) + fileSuffix
+	if label != "" {
+		name = filePrefix + label + "-" + now.Format(fileTime) + fileSuffix
+	}
+	path := filepath.Join(m.dir, name)
+
+	// VACUUM INTO refuses to overwrite files, and writing to a temporary
+	// name keeps half-written backups from looking complete
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+
+	_, err = m.db.Exec("VACUUM INTO ?", tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("could not back up database: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not back up database: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not back up database: %w", err)
+	}
+
+	return &File{Name: name, Path: path, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// List returns the backups in the backup directory, newest first.
+func (m *Manager) List() ([]*File, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []*File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list backups: %w", err)
+	}
+
+	files := []*File{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		t, err := time.Parse(fileTime, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("could not list backups: %w", err)
+		}
+
+		files = append(files, &File{
+			Name:      name,
+			Path:      filepath.Join(m.dir, name),
+			Size:      info.Size(),
+			CreatedAt: t,
+		})
+	}
+
+	slices.SortFunc(files, func(a, b *File) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return files, nil
+}
+
+// prune deletes the backups the retention policy doesn't keep. The latest
+// backup is always kept.
+func (m *Manager) prune() error {
+	files, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	for i, f := range files {
+		t := f.CreatedAt.Local()
+		day := t.Format(time.DateOnly)
+		year, w := t.ISOWeek()
+		week := fmt.Sprintf("%d-%02d", year, w)
+
+		keep := i == 0
+		if !days[day] && len(days) < m.retention.Daily {
+			days[day] = true
+			keep = true
+		}
+		if !weeks[week] && len(weeks) < m.retention.Weekly {
+			weeks[week] = true
+			keep = true
+		}
+
+		if !keep {
+			err = os.Remove(f.Path)
+			if err != nil {
+				return fmt.Errorf("could not delete old backup: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Verify checks that the database file at path is intact with PRAGMA
+// integrity_check, returning its schema version if so.
+func Verify(path string) (int, error) {
+	// opening a missing file read-only fails with a confusing error
+	_, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// characters like ? and # in the path mustn't be read as part of the URI
+	u := &url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite", u.String())
+	if err != nil {
+		return 0, fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return 0, fmt.Errorf("could not check %s: %w", path, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		err = rows.Scan(&msg)
+		if err != nil {
+			return 0, fmt.Errorf("could not check %s: %w", path, err)
+		}
+
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return 0, fmt.Errorf("could not check %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return 0, fmt.Errorf("%s failed its integrity check:\n%s", path, strings.Join(problems, "\n"))
+	}
+
+	var version int
+	err = db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("could not check %s: %w", path, err)
+	}
+
+	return version, nil
+}
+
+// Replace swaps the database file at dbPath for a copy of the backup at
+// path. Nothing may have the database open while it is replaced.
+func Replace(dbPath string, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open backup: %w", err)
+	}
+	defer src.Close()
+
+	// copy next to the database first so the final rename can't leave a
+	// partial file in its place
+	tmp := dbPath + ".restore"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("could not restore backup: %w", err)
+	}
+
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not restore backup: %w", err)
+	}
+
+	// a write-ahead log left over from the old database would be replayed
+	// into the restored one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		err = os.Remove(dbPath + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			os.Remove(tmp)
+			return fmt.Errorf("could not restore backup: %w", err)
+		}
+	}
+
+	err = os.Rename(tmp, dbPath)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not restore backup: %w", err)
+	}
+
+	return nil
+}
@@ -1,90 +1,336 @@
 package bookmark
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/cmessinides/mnemonic/internal/metadata"
 	"github.com/cmessinides/mnemonic/internal/pagination"
+	"github.com/cmessinides/mnemonic/internal/query"
+	"github.com/cmessinides/mnemonic/internal/sqltime"
 	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
 	"github.com/jmoiron/sqlx"
 	"modernc.org/sqlite"
 	sqlite3 "modernc.org/sqlite/lib"
 )
 
 type Bookmark struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	URL       string    `json:"url"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
-	Archived  bool      `json:"archived" db:"archived"`
-	Tags      tag.Tags  `json:"tags"`
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Description is a short summary of the bookmark, in plain text.
+	Description string `json:"description"`
+	// Notes are longer notes about the bookmark, in Markdown.
+	Notes string `json:"notes"`
+	// Metadata is what the bookmarked page says about itself, or nil if it
+	// hasn't been fetched.
+	Metadata *metadata.Metadata `json:"metadata"`
+	// Icon and Preview are the names of the bookmark's favicon and preview
+	// image in the media cache, or empty if it has none.
+	Icon    string `json:"icon"`
+	Preview string `json:"preview"`
+	// LinkStatus is what was found the last time the page was checked.
+	LinkStatus LinkStatus `json:"linkStatus" db:"link_status"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+	Archived   bool       `json:"archived" db:"archived"`
+	Tags       tag.Tags   `json:"tags"`
+}
+
+// MaxDescriptionLength is the longest a description should be, in
+// characters. Anything longer belongs in the notes.
+const MaxDescriptionLength = 500
+
+// BookmarkInit is a new bookmark.
+type BookmarkInit struct {
+	Title       string
+	URL         string
+	Description string
+	Notes       string
+	Metadata    *metadata.Metadata
+	Tags        []string
 }
 
 type BookmarkPatch struct {
-	ID       int64
-	Title    *string
-	URL      *string
-	Archived *bool
-	Tags     tag.Tags
+	ID          int64
+	Title       *string
+	URL         *string
+	Description *string
+	Notes       *string
+	Metadata    *metadata.Metadata
+	Archived    *bool
+	Tags        tag.Tags
+	// UnmodifiedSince, if set, makes the update fail with a *ModifiedError
+	// when the bookmark was updated at any other time, so that a patch
+	// worked out from an earlier read doesn't undo a change made since.
+	UnmodifiedSince *time.Time
+}
+
+// Filter narrows a list of bookmarks. The zero value matches every active
+// bookmark.
+type Filter struct {
+	// Tags matches bookmarks with any of the tags, or all of them when
+	// TagMatch is TagMatchAll. A bookmark has a tag when it has the tag or
+	// any tag beneath it.
+	Tags     []string
+	TagMatch TagMatch
+	// State matches bookmarks by whether they are archived, defaulting to
+	// StateActive.
+	State State
+	// Domain matches bookmarks on the domain or any of its subdomains, like
+	// a site: search.
+	Domain string
+	// CreatedAfter and CreatedBefore match bookmarks created at or after,
+	// and before, the given times. Zero times aren't used.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// LinkStatus matches bookmarks whose page was last found to have the
+	// status. The empty status matches any.
+	LinkStatus LinkStatus
+}
+
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any"
+	TagMatchAll TagMatch = "all"
+)
+
+// LinkStatus sums up the latest checks of whether a bookmark's page still
+// exists.
+type LinkStatus string
+
+const (
+	LinkUnchecked  LinkStatus = "unchecked"
+	LinkOK         LinkStatus = "ok"
+	LinkRedirected LinkStatus = "redirected"
+	LinkBroken     LinkStatus = "broken"
+)
+
+func ParseLinkStatus(s string) (LinkStatus, error) {
+	switch ls := LinkStatus(s); ls {
+	case LinkUnchecked, LinkOK, LinkRedirected, LinkBroken:
+		return ls, nil
+	default:
+		return "", fmt.Errorf("unknown link status %q", s)
+	}
+}
+
+type State string
+
+const (
+	StateActive   State = "active"
+	StateArchived State = "archived"
+	StateAll      State = "all"
+)
+
+// sql returns a boolean SQL expression for the filter against bookmarks b.
+func (f Filter) sql() (string, []any) {
+	var where string
+	switch f.State {
+	case StateArchived:
+		where = "b.archived_at IS NOT NULL"
+	case StateAll:
+		where = "1"
+	default:
+		where = "b.archived_at IS NULL"
+	}
+
+	// tags and domains are matched the same way as in searches
+	var fields query.And
+	if len(f.Tags) > 0 {
+		var tags []query.Node
+		for _, t := range f.Tags {
+			tags = append(tags, query.Field{Name: query.FieldTag, Value: t})
+		}
+
+		if f.TagMatch == TagMatchAll {
+			fields = append(fields, query.And(tags))
+		} else {
+			fields = append(fields, query.Or(tags))
+		}
+	}
+	if f.Domain != "" {
+		fields = append(fields, query.Field{Name: query.FieldSite, Value: strings.ToLower(f.Domain)})
+	}
+
+	var args []any
+	if len(fields) > 0 {
+		qs := (&query.Query{Root: fields}).SQL()
+		where += " AND " + qs.Where
+		args = qs.Args
+	}
+
+	if !f.CreatedAfter.IsZero() {
+		where += " AND julianday(b.created_at) >= julianday(?)"
+		args = append(args, sqltime.Julian(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		where += " AND julianday(b.created_at) < julianday(?)"
+		args = append(args, sqltime.Julian(f.CreatedBefore))
+	}
+	if f.LinkStatus != "" {
+		where += " AND b.link_status = ?"
+		args = append(args, f.LinkStatus)
+	}
+
+	return where, args
+}
+
+// Sort orders a list of bookmarks. The zero value orders them by when they
+// were created, oldest first.
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+type SortField string
+
+const (
+	SortCreated SortField = "created"
+	SortUpdated SortField = "updated"
+	SortTitle   SortField = "title"
+	SortDomain  SortField = "domain"
+)
+
+func ParseSortField(s string) (SortField, error) {
+	switch f := SortField(s); f {
+	case SortCreated, SortUpdated, SortTitle, SortDomain:
+		return f, nil
+	case "":
+		return SortCreated, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q (expected created, updated, title, or domain)", s)
+	}
+}
+
+// sql returns an ORDER BY list for the sort against bookmarks b. Every
+// order ends with the ID so that it is stable.
+func (s Sort) sql() string {
+	var columns []string
+	switch s.Field {
+	case SortUpdated:
+		columns = []string{"b.updated_at", "b.id"}
+	case SortTitle:
+		columns = []string{"b.title COLLATE NOCASE", "b.id"}
+	case SortDomain:
+		columns = []string{"b.url_host", "b.id"}
+	default:
+		columns = []string{"julianday(b.created_at)", "b.id"}
+	}
+
+	if s.Desc {
+		for i := range columns {
+			columns[i] += " DESC"
+		}
+	}
+
+	return strings.Join(columns, ", ")
 }
 
 type BookmarkStore interface {
-	Create(title string, url string, tags []string) (*Bookmark, error)
-	Update(patch BookmarkPatch) error
-	Get(id int64) (*Bookmark, error)
-	GetByURL(url string) (*Bookmark, error)
-	GetPage(page uint64, pageSize uint64) (*pagination.Page[*Bookmark], error)
-	Delete(id int64) error
+	Create(ctx context.Context, init BookmarkInit) (*Bookmark, error)
+	Update(ctx context.Context, patch BookmarkPatch) (*Bookmark, error)
+	Import(ctx context.Context, b *Bookmark, overwrite bool) (*Bookmark, error)
+	Get(ctx context.Context, id int64) (*Bookmark, error)
+	GetByURL(ctx context.Context, url string) (*Bookmark, error)
+	GetPage(ctx context.Context, page uint64, pageSize uint64, filter Filter, sort Sort) (*pagination.Page[*Bookmark], error)
+	GetCursorPage(ctx context.Context, params pagination.CursorParams, filter Filter) (*pagination.CursorPage[*Bookmark], error)
+	Search(ctx context.Context, q *query.Query, page uint64, pageSize uint64) (*pagination.Page[*SearchResult], error)
+	Each(ctx context.Context, filter Filter, fn func(b *Bookmark) error) error
+	Delete(ctx context.Context, id int64) error
+	PendingMedia(ctx context.Context, limit int) ([]*Bookmark, error)
+	SetMedia(ctx context.Context, id int64, media Media) error
+	UpdateURLKeys(ctx context.Context) (int, error)
+	Dedupe(ctx context.Context, dryRun bool) ([]*Duplicate, error)
 }
 
-func NewSQLiteBookmarkStore(db *sql.DB) *SQLiteBookmarkStore {
-	return &SQLiteBookmarkStore{db: sqlx.NewDb(db, "sqlite")}
+func NewSQLiteBookmarkStore(db *sql.DB, tagRules tag.Rules, urlRules urlnorm.Rules) *SQLiteBookmarkStore {
+	return &SQLiteBookmarkStore{
+		db:       sqlx.NewDb(db, "sqlite"),
+		tagRules: tagRules,
+		urlRules: urlRules,
+	}
 }
 
 type SQLiteBookmarkStore struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	tagRules tag.Rules
+	urlRules urlnorm.Rules
 }
 
-//go:embed schema.sql
-var schema string
+func (bs *SQLiteBookmarkStore) Create(ctx context.Context, init BookmarkInit) (*Bookmark, error) {
+	now := time.Now()
 
-func (bs *SQLiteBookmarkStore) Init() error {
-	_, err := bs.db.Exec(schema)
+	tags, err := bs.tagRules.Parse(init.Tags)
 	if err != nil {
-		return fmt.Errorf("failed to initialize bookmarks schema: %w", err)
+		return nil, err
 	}
 
-	return nil
-}
+	init.URL = bs.urlRules.Canonicalize(init.URL)
+	key := bs.urlRules.Key(init.URL)
+	init.Title = StripControl(init.Title)
+	init.Description = StripControl(init.Description)
+	init.Notes = StripControl(init.Notes)
 
-func (bs *SQLiteBookmarkStore) Create(title string, url string, tags []string) (*Bookmark, error) {
-	now := time.Now()
-	b := new(Bookmark)
+	tx, err := bs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark: %w", err)
+	}
+	defer tx.Rollback()
 
-	err := bs.db.Get(b, "INSERT INTO bookmarks (title, url, tags, created_at, updated_at) VALUES (?, ?, ?, ?, ?) RETURNING id, title, url, tags, created_at, updated_at", title, url, tag.Tags(tags), now, now)
+	_, err = findDuplicate(ctx, tx, key, 0)
 	if err != nil {
-		var sqliteErr *sqlite.Error
-		if errors.As(err, &sqliteErr) {
-			if sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteErr.Error(), "bookmarks.url") {
-				return nil, &URLExistsError{
-					URL: url,
-					Err: err,
-				}
+		return nil, err
+	}
+
+	var id int64
+	err = tx.GetContext(ctx, &id, `
+        INSERT INTO bookmarks (title, url, url_key, url_host, description, notes, metadata, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id
+    `, init.Title, init.URL, key, urlnorm.Host(init.URL), init.Description, init.Notes, init.Metadata, now, now)
+	if err != nil {
+		if isDuplicateUrl(err) {
+			return nil, &URLExistsError{
+				URL: init.URL,
+				Err: err,
 			}
 		}
 
 		return nil, fmt.Errorf("failed to create bookmark: %w", err)
 	}
 
+	tags, err = tag.ResolveAliases(ctx, tx, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setTags(ctx, tx, id, tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark: %w", err)
+	}
+
+	b, err := getBookmark(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bookmark: %w", err)
+	}
+
 	return b, nil
 }
 
-func (bs *SQLiteBookmarkStore) Update(patch BookmarkPatch) error {
+func (bs *SQLiteBookmarkStore) Update(ctx context.Context, patch BookmarkPatch) (*Bookmark, error) {
 	now := time.Now()
 
 	args := []any{}
@@ -92,91 +338,518 @@ func (bs *SQLiteBookmarkStore) Update(patch BookmarkPatch) error {
 	query.WriteString("UPDATE bookmarks SET ")
 
 	if patch.Title != nil {
-		args = append(args, *patch.Title)
+		args = append(args, StripControl(*patch.Title))
 		query.WriteString("title = ?, ")
 	}
 
+	var key string
 	if patch.URL != nil {
-		args = append(args, *patch.URL)
-		query.WriteString("url = ?, ")
+		u := bs.urlRules.Canonicalize(*patch.URL)
+		patch.URL = &u
+		key = bs.urlRules.Key(u)
+		args = append(args, u, key, urlnorm.Host(u))
+		query.WriteString("url = ?, url_key = ?, url_host = ?, ")
+	}
+
+	if patch.Description != nil {
+		args = append(args, StripControl(*patch.Description))
+		query.WriteString("description = ?, ")
+	}
+
+	if patch.Notes != nil {
+		args = append(args, StripControl(*patch.Notes))
+		query.WriteString("notes = ?, ")
+	}
+
+	if patch.Metadata != nil {
+		args = append(args, patch.Metadata)
+		query.WriteString("metadata = ?, ")
+	}
+
+	if patch.URL != nil || patch.Metadata != nil {
+		// the icon and preview come from the page, so look for them again
+		query.WriteString("media_checked_at = NULL, ")
+	}
+
+	if patch.URL != nil {
+		// a new page hasn't been checked, whatever happened to the old one
+		query.WriteString("link_status = 'unchecked', link_checked_at = NULL, ")
 	}
 
 	if patch.Archived != nil {
 		if *patch.Archived {
+			// keep the original archive date if already archived
 			args = append(args, now)
+			query.WriteString("archived_at = coalesce(archived_at, ?), ")
 		} else {
-			args = append(args, nil)
+			query.WriteString("archived_at = NULL, ")
 		}
-		query.WriteString("archived_at = ?, ")
 	}
 
 	if patch.Tags != nil {
-		args = append(args, patch.Tags)
-		query.WriteString("tags = ?, ")
+		tags, err := bs.tagRules.Parse(patch.Tags)
+		if err != nil {
+			return nil, err
+		}
+		patch.Tags = tags
 	}
 
-	if len(args) == 0 {
+	if patch.Title == nil && patch.URL == nil && patch.Description == nil && patch.Notes == nil && patch.Metadata == nil && patch.Archived == nil && patch.Tags == nil {
 		// nothing to update
-		return nil
+		return bs.Get(ctx, patch.ID)
 	}
 
 	query.WriteString("updated_at = ? WHERE id = ?")
 	args = append(args, now, patch.ID)
 
-	result, err := bs.db.Exec(query.String(), args...)
+	tx, err := bs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update bookmark: %w", err)
+	}
+	defer tx.Rollback()
+
+	if patch.UnmodifiedSince != nil {
+		var updatedAt time.Time
+		err = tx.GetContext(ctx, &updatedAt, "SELECT updated_at FROM bookmarks WHERE id = ?", patch.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &NotFoundError{Field: "id", Value: patch.ID, Err: err}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not select bookmark: %w", err)
+		}
+		if !updatedAt.Equal(*patch.UnmodifiedSince) {
+			return nil, &ModifiedError{ID: patch.ID}
+		}
+	}
+
+	if patch.URL != nil {
+		_, err = findDuplicate(ctx, tx, key, patch.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, query.String(), args...)
 	if err != nil {
 		if isDuplicateUrl(err) {
-			return &URLExistsError{
+			return nil, &URLExistsError{
 				URL: *patch.URL,
 				Err: err,
 			}
 		} else {
-			return fmt.Errorf("failed to update bookmark: %w", err)
+			return nil, fmt.Errorf("failed to update bookmark: %w", err)
 		}
 	}
 
 	n, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("could not update bookmark: %w", err)
+		return nil, fmt.Errorf("could not update bookmark: %w", err)
 	}
 
 	if n == 0 {
-		return &NotFoundError{
+		return nil, &NotFoundError{
 			Field: "id",
 			Value: patch.ID,
 		}
 	}
 
+	if patch.Tags != nil {
+		patch.Tags, err = tag.ResolveAliases(ctx, tx, patch.Tags)
+		if err != nil {
+			return nil, err
+		}
+
+		err = setTags(ctx, tx, patch.ID, patch.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update bookmark: %w", err)
+		}
+	}
+
+	b, err := getBookmark(ctx, tx, patch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update bookmark: %w", err)
+	}
+
+	return b, nil
+}
+
+// Import creates a bookmark brought in from elsewhere, keeping its
+// timestamps and archived state. It fails with a *URLExistsError if a
+// bookmark of the same page exists, unless overwrite is set, in which case
+// that bookmark is replaced by b but keeps its ID.
+func (bs *SQLiteBookmarkStore) Import(ctx context.Context, b *Bookmark, overwrite bool) (*Bookmark, error) {
+	tags, err := bs.tagRules.Parse(b.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := b.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	updatedAt := b.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+
+	var archivedAt *time.Time
+	if b.Archived {
+		archivedAt = &updatedAt
+	}
+
+	u := bs.urlRules.Canonicalize(b.URL)
+	key := bs.urlRules.Key(u)
+	title, description, notes := StripControl(b.Title), StripControl(b.Description), StripControl(b.Notes)
+
+	tx, err := bs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import bookmark: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := findDuplicate(ctx, tx, key, 0)
+	if err != nil && !(overwrite && IsURLExists(err)) {
+		return nil, err
+	}
+
+	if id != 0 {
+		_, err = tx.ExecContext(ctx, `
+            UPDATE bookmarks SET
+                title = ?,
+                url = ?,
+                url_key = ?,
+                url_host = ?,
+                description = ?,
+                notes = ?,
+                created_at = ?,
+                updated_at = ?,
+                archived_at = ?
+            WHERE id = ?
+        `, title, u, key, urlnorm.Host(u), description, notes, createdAt, updatedAt, archivedAt, id)
+	} else {
+		err = tx.GetContext(ctx, &id, `
+            INSERT INTO bookmarks (title, url, url_key, url_host, description, notes, created_at, updated_at, archived_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+            RETURNING id
+        `, title, u, key, urlnorm.Host(u), description, notes, createdAt, updatedAt, archivedAt)
+	}
+	if err != nil {
+		if isDuplicateUrl(err) {
+			return nil, &URLExistsError{
+				URL: u,
+				Err: err,
+			}
+		}
+
+		return nil, fmt.Errorf("failed to import bookmark: %w", err)
+	}
+
+	tags, err = tag.ResolveAliases(ctx, tx, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setTags(ctx, tx, id, tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import bookmark: %w", err)
+	}
+
+	imported, err := getBookmark(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to import bookmark: %w", err)
+	}
+
+	return imported, nil
+}
+
+// setTags replaces the tags of a bookmark, creating any tags that don't
+// exist yet.
+func setTags(ctx context.Context, tx *sqlx.Tx, id int64, tags []string) error {
+	names := tag.Tags(tags)
+	if names == nil {
+		names = tag.Tags{}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+        DELETE FROM bookmark_tags
+        WHERE bookmark_id = ?
+        AND tag_id NOT IN (SELECT t.id FROM tags t, json_each(?) j WHERE t.name = j.value)
+    `, id, names)
+	if err != nil {
+		return fmt.Errorf("could not remove tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO tags (name)
+        SELECT DISTINCT value FROM json_each(?) WHERE true
+        ON CONFLICT (name) DO NOTHING
+    `, names)
+	if err != nil {
+		return fmt.Errorf("could not create tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id)
+        SELECT ?, t.id FROM tags t, json_each(?) j WHERE t.name = j.value
+    `, id, names)
+	if err != nil {
+		return fmt.Errorf("could not add tags: %w", err)
+	}
+
 	return nil
 }
 
-func (bs *SQLiteBookmarkStore) GetPage(page uint64, pageSize uint64) (*pagination.Page[*Bookmark], error) {
+func (bs *SQLiteBookmarkStore) GetPage(ctx context.Context, page uint64, pageSize uint64, filter Filter, sort Sort) (*pagination.Page[*Bookmark], error) {
 	bookmarks := []*Bookmark{}
 
+	where, args := filter.sql()
+
 	limit := pageSize
-	offset := (page - 1) * pageSize
-	err := bs.db.Select(&bookmarks, "SELECT * FROM active_bookmarks LIMIT ? OFFSET ?", limit, offset)
+	offset := pagination.Offset(page, pageSize)
+	err := bs.db.SelectContext(ctx, &bookmarks, `
+        SELECT a.*
+        FROM bookmarks b
+        JOIN all_bookmarks a ON a.id = b.id
+        WHERE `+where+`
+        ORDER BY `+sort.sql()+`
+        LIMIT ? OFFSET ?
+    `, append(args, limit, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("could not select bookmarks: %w", err)
 	}
 
 	var total uint64
-	err = bs.db.Get(&total, "SELECT COUNT(1) FROM active_bookmarks")
+	err = bs.db.GetContext(ctx, &total, "SELECT COUNT(1) FROM bookmarks b WHERE "+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("could not select bookmark total: %w", err)
 	}
 
-	return &pagination.Page[*Bookmark]{
-		Items:      bookmarks,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: max(total/pageSize, 1),
-	}, nil
+	return pagination.NewPage(bookmarks, page, pageSize, total), nil
+}
+
+// GetCursorPage returns the page of bookmarks matching filter that starts at
+// params.Cursor, oldest first unless params.Desc is set. Pages are found by
+// comparing creation times and IDs rather than skipping rows, so they stay
+// fast deep into the list and don't repeat bookmarks when others are added
+// in the meantime.
+func (bs *SQLiteBookmarkStore) GetCursorPage(ctx context.Context, params pagination.CursorParams, filter Filter) (*pagination.CursorPage[*Bookmark], error) {
+	bookmarks := []*Bookmark{}
+	cursor := params.Cursor
+
+	where, args := filter.sql()
+	countWhere, countArgs := where, slices.Clone(args)
+
+	// reading back towards the start of the list runs the query in the
+	// opposite order, and the results are reversed afterwards
+	sort := Sort{Field: SortCreated, Desc: params.Desc}
+	if cursor != nil && cursor.Before {
+		sort.Desc = !sort.Desc
+	}
+
+	if cursor != nil {
+		op := ">"
+		if sort.Desc {
+			op = "<"
+		}
+		// SQLite only searches an expression index by its first column, so
+		// the time is bounded on its own as well as with the ID
+		createdAt := sqltime.Julian(cursor.CreatedAt)
+		where += " AND julianday(b.created_at) " + op + "= julianday(?)"
+		where += " AND (julianday(b.created_at), b.id) " + op + " (julianday(?), ?)"
+		args = append(args, createdAt, createdAt, cursor.ID)
+	}
+
+	// fetch one extra bookmark to find out whether there is another page
+	err := bs.db.SelectContext(ctx, &bookmarks, `
+        SELECT a.*
+        FROM bookmarks b
+        JOIN all_bookmarks a ON a.id = b.id
+        WHERE `+where+`
+        ORDER BY `+sort.sql()+`
+        LIMIT ?
+    `, append(args, params.PageSize+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmarks: %w", err)
+	}
+
+	more := uint64(len(bookmarks)) > params.PageSize
+	if more {
+		bookmarks = bookmarks[:params.PageSize]
+	}
+	if cursor != nil && cursor.Before {
+		slices.Reverse(bookmarks)
+	}
+
+	p := &pagination.CursorPage[*Bookmark]{
+		Items:    bookmarks,
+		PageSize: params.PageSize,
+	}
+
+	// going forward, there is a previous page whenever we started from a
+	// cursor, and a next page if there were more bookmarks; going back,
+	// it's the other way around
+	var hasPrev, hasNext bool
+	switch {
+	case cursor == nil:
+		hasNext = more
+	case cursor.Before:
+		hasPrev, hasNext = more, true
+	default:
+		hasPrev, hasNext = true, more
+	}
+
+	if len(bookmarks) > 0 {
+		first, last := bookmarks[0], bookmarks[len(bookmarks)-1]
+		if hasPrev {
+			p.Prev = (&pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Before: true}).String()
+		}
+		if hasNext {
+			p.Next = (&pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}).String()
+		}
+	} else if cursor != nil {
+		// past either end of the list, the way back starts at the cursor
+		back := *cursor
+		back.Before = !cursor.Before
+		if cursor.Before {
+			p.Next = back.String()
+		} else {
+			p.Prev = back.String()
+		}
+	}
+
+	if !params.SkipTotal {
+		var total uint64
+		err = bs.db.GetContext(ctx, &total, "SELECT COUNT(1) FROM bookmarks b WHERE "+countWhere, countArgs...)
+		if err != nil {
+			return nil, fmt.Errorf("could not select bookmark total: %w", err)
+		}
+		p.Total = &total
+	}
+
+	return p, nil
+}
+
+// Each calls fn with every bookmark matching filter, oldest first, without
+// loading them all into memory. It stops at the first error fn returns.
+func (bs *SQLiteBookmarkStore) Each(ctx context.Context, filter Filter, fn func(b *Bookmark) error) error {
+	where, args := filter.sql()
+	rows, err := bs.db.QueryxContext(ctx, `
+        SELECT a.*
+        FROM bookmarks b
+        JOIN all_bookmarks a ON a.id = b.id
+        WHERE `+where+`
+        ORDER BY b.created_at, b.id
+    `, args...)
+	if err != nil {
+		return fmt.Errorf("could not select bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b := &Bookmark{}
+		err = rows.StructScan(b)
+		if err != nil {
+			return fmt.Errorf("could not read bookmark: %w", err)
+		}
+
+		err = fn(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("could not select bookmarks: %w", err)
+	}
+
+	return nil
+}
+
+func (bs *SQLiteBookmarkStore) Search(ctx context.Context, q *query.Query, page uint64, pageSize uint64) (*pagination.Page[*SearchResult], error) {
+	results := []*SearchResult{}
+
+	if q.IsEmpty() {
+		return pagination.NewPage(results, page, pageSize, 0), nil
+	}
+
+	qs := q.SQL()
+	limit := pageSize
+	offset := pagination.Offset(page, pageSize)
+
+	var selectQuery, countQuery string
+	var selectArgs, countArgs []any
+	if qs.Match != "" {
+		selectQuery = `
+            SELECT a.*,
+                highlight(bookmarks_fts, 0, ?, ?) title_highlight,
+                snippet(bookmarks_fts, -1, ?, ?, '…', 16) snippet
+            FROM bookmarks_fts
+            JOIN bookmarks b ON b.id = bookmarks_fts.rowid
+            JOIN all_bookmarks a ON a.id = b.id
+            WHERE bookmarks_fts MATCH ? AND ` + qs.Where + `
+            ORDER BY rank
+            LIMIT ? OFFSET ?
+        `
+		selectArgs = append([]any{highlightStart, highlightEnd, highlightStart, highlightEnd, qs.Match}, qs.Args...)
+		countQuery = `
+            SELECT COUNT(1)
+            FROM bookmarks_fts
+            JOIN bookmarks b ON b.id = bookmarks_fts.rowid
+            WHERE bookmarks_fts MATCH ? AND ` + qs.Where
+		countArgs = append([]any{qs.Match}, qs.Args...)
+	} else {
+		// without any required text there's nothing to rank or highlight
+		selectQuery = `
+            SELECT a.*, b.title title_highlight, '' snippet
+            FROM bookmarks b
+            JOIN all_bookmarks a ON a.id = b.id
+            WHERE ` + qs.Where + `
+            ORDER BY b.created_at DESC
+            LIMIT ? OFFSET ?
+        `
+		selectArgs = qs.Args
+		countQuery = "SELECT COUNT(1) FROM bookmarks b WHERE " + qs.Where
+		countArgs = qs.Args
+	}
+
+	err := bs.db.SelectContext(ctx, &results, selectQuery, append(selectArgs, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not search bookmarks: %w", err)
+	}
+
+	for _, r := range results {
+		r.TitleHighlight = highlightHTML(r.TitleHighlight)
+		r.Snippet = highlightHTML(r.Snippet)
+	}
+
+	var total uint64
+	err = bs.db.GetContext(ctx, &total, countQuery, countArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("could not select search result total: %w", err)
+	}
+
+	return pagination.NewPage(results, page, pageSize, total), nil
 }
 
-func (bs *SQLiteBookmarkStore) Get(id int64) (*Bookmark, error) {
+func (bs *SQLiteBookmarkStore) Get(ctx context.Context, id int64) (*Bookmark, error) {
+	return getBookmark(ctx, bs.db, id)
+}
+
+func getBookmark(ctx context.Context, q sqlx.QueryerContext, id int64) (*Bookmark, error) {
 	bookmark := &Bookmark{}
-	err := bs.db.Get(bookmark, `
+	err := sqlx.GetContext(ctx, q, bookmark, `
         SELECT * FROM all_bookmarks WHERE id = ?
     `, id)
 	if err != nil {
@@ -194,11 +867,18 @@ func (bs *SQLiteBookmarkStore) Get(id int64) (*Bookmark, error) {
 	return bookmark, nil
 }
 
-func (bs *SQLiteBookmarkStore) GetByURL(url string) (*Bookmark, error) {
+func (bs *SQLiteBookmarkStore) GetByURL(ctx context.Context, url string) (*Bookmark, error) {
 	bookmark := &Bookmark{}
-	err := bs.db.Get(bookmark, `
-        SELECT * FROM all_bookmarks WHERE url = ?
-    `, url)
+	// bookmarks saved before their keys were worked out are still found by
+	// their exact URL
+	err := bs.db.GetContext(ctx, bookmark, `
+        SELECT a.*
+        FROM bookmarks b
+        JOIN all_bookmarks a ON a.id = b.id
+        WHERE b.url_key = ? OR b.url = ?
+        ORDER BY b.created_at, b.id
+        LIMIT 1
+    `, bs.urlRules.Key(url), url)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &NotFoundError{
@@ -214,8 +894,8 @@ func (bs *SQLiteBookmarkStore) GetByURL(url string) (*Bookmark, error) {
 	return bookmark, nil
 }
 
-func (bs *SQLiteBookmarkStore) Delete(id int64) error {
-	result, err := bs.db.Exec(`DELETE FROM bookmarks WHERE id = ?`, id)
+func (bs *SQLiteBookmarkStore) Delete(ctx context.Context, id int64) error {
+	result, err := bs.db.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete bookmark from database: %w", err)
 	}
@@ -232,6 +912,56 @@ func (bs *SQLiteBookmarkStore) Delete(id int64) error {
 	return nil
 }
 
+// Media is the images found for a bookmark, by their names in the media
+// cache.
+type Media struct {
+	Icon    string
+	Preview string
+}
+
+// PendingMedia returns up to limit bookmarks, archived or not, whose icon
+// and preview haven't been looked for since they were created or their
+// page changed.
+func (bs *SQLiteBookmarkStore) PendingMedia(ctx context.Context, limit int) ([]*Bookmark, error) {
+	bookmarks := []*Bookmark{}
+	err := bs.db.SelectContext(ctx, &bookmarks, `
+        SELECT a.*
+        FROM bookmarks b
+        JOIN all_bookmarks a ON a.id = b.id
+        WHERE b.media_checked_at IS NULL
+        ORDER BY b.id
+        LIMIT ?
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmarks: %w", err)
+	}
+
+	return bookmarks, nil
+}
+
+// SetMedia stores the images found for a bookmark and marks it as checked.
+// It doesn't count as an update, so the bookmark's update time is kept.
+func (bs *SQLiteBookmarkStore) SetMedia(ctx context.Context, id int64, media Media) error {
+	res, err := bs.db.ExecContext(ctx, `
+        UPDATE bookmarks
+        SET icon = ?, preview = ?, media_checked_at = ?
+        WHERE id = ?
+    `, media.Icon, media.Preview, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("could not set bookmark media: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not set bookmark media: %w", err)
+	}
+	if n == 0 {
+		return &NotFoundError{Field: "id", Value: id}
+	}
+
+	return nil
+}
+
 func isDuplicateUrl(err error) bool {
 	var sqliteErr *sqlite.Error
 
@@ -0,0 +1,241 @@
+package bookmark
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
+	"github.com/jmoiron/sqlx"
+)
+
+// Duplicate is a group of bookmarks of the same page. Deduping keeps the
+// oldest one and merges the others into it.
+type Duplicate struct {
+	Kept   *Bookmark
+	Merged []*Bookmark
+}
+
+// findDuplicate looks for a bookmark other than exceptID with the URL key
+// key, returning its ID and a *URLExistsError if there is one.
+func findDuplicate(ctx context.Context, q sqlx.QueryerContext, key string, exceptID int64) (int64, error) {
+	var existing struct {
+		ID  int64
+		URL string
+	}
+	err := sqlx.GetContext(ctx, q, &existing, `
+        SELECT id, url
+        FROM bookmarks
+        WHERE url_key = ? AND id != ?
+        ORDER BY created_at, id
+        LIMIT 1
+    `, key, exceptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not look for duplicate bookmarks: %w", err)
+	}
+
+	return existing.ID, &URLExistsError{URL: existing.URL}
+}
+
+// UpdateURLKeys works out the URL key of every bookmark whose key is
+// missing or was worked out with different URL rules, and returns how many
+// changed. Keys that now match another bookmark's are kept anyway, so the
+// duplicates can be found with Dedupe. Missing URL hosts are filled in
+// too, but aren't counted.
+func (bs *SQLiteBookmarkStore) UpdateURLKeys(ctx context.Context) (int, error) {
+	tx, err := bs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update URL keys: %w", err)
+	}
+	defer tx.Rollback()
+
+	n, err := bs.updateURLKeys(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to update URL keys: %w", err)
+	}
+
+	return n, nil
+}
+
+func (bs *SQLiteBookmarkStore) updateURLKeys(ctx context.Context, tx *sqlx.Tx) (int, error) {
+	var rows []struct {
+		ID      int64
+		URL     string
+		URLKey  sql.NullString `db:"url_key"`
+		URLHost sql.NullString `db:"url_host"`
+	}
+	err := tx.SelectContext(ctx, &rows, "SELECT id, url, url_key, url_host FROM bookmarks")
+	if err != nil {
+		return 0, fmt.Errorf("could not select bookmarks: %w", err)
+	}
+
+	changed := 0
+	for _, r := range rows {
+		key := bs.urlRules.Key(r.URL)
+		keyChanged := !r.URLKey.Valid || r.URLKey.String != key
+		if !keyChanged && r.URLHost.Valid {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE bookmarks SET url_key = ?, url_host = ? WHERE id = ?", key, urlnorm.Host(r.URL), r.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update URL key: %w", err)
+		}
+		if keyChanged {
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// Dedupe finds bookmarks of the same page, going by the URL rules, and
+// merges each group into its oldest bookmark in a single transaction. The
+// kept bookmark gains the tags of the others, their descriptions and
+// metadata where it has none, and their notes after its own. It stays
+// active if any of them were. With dryRun set, the groups are found but
+// nothing is changed.
+func (bs *SQLiteBookmarkStore) Dedupe(ctx context.Context, dryRun bool) ([]*Duplicate, error) {
+	tx, err := bs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dedupe bookmarks: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = bs.updateURLKeys(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err = tx.SelectContext(ctx, &keys, `
+        SELECT url_key
+        FROM bookmarks
+        GROUP BY url_key
+        HAVING count(*) > 1
+        ORDER BY min(created_at)
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("could not find duplicate bookmarks: %w", err)
+	}
+
+	duplicates := []*Duplicate{}
+	for _, key := range keys {
+		group := []*Bookmark{}
+		err = tx.SelectContext(ctx, &group, `
+            SELECT a.*
+            FROM bookmarks b
+            JOIN all_bookmarks a ON a.id = b.id
+            WHERE b.url_key = ?
+            ORDER BY b.created_at, b.id
+        `, key)
+		if err != nil {
+			return nil, fmt.Errorf("could not select duplicate bookmarks: %w", err)
+		}
+
+		d := &Duplicate{Kept: group[0], Merged: group[1:]}
+		if !dryRun {
+			d.Kept, err = mergeDuplicate(ctx, tx, d)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		duplicates = append(duplicates, d)
+	}
+
+	if dryRun {
+		return duplicates, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to dedupe bookmarks: %w", err)
+	}
+
+	return duplicates, nil
+}
+
+// mergeDuplicate merges the bookmarks in d into the kept one and deletes
+// them, returning the kept bookmark as it is afterwards.
+func mergeDuplicate(ctx context.Context, tx *sqlx.Tx, d *Duplicate) (*Bookmark, error) {
+	kept := *d.Kept
+	ids := make([]int64, 0, len(d.Merged))
+	notes := []string{}
+	if n := strings.TrimSpace(kept.Notes); n != "" {
+		notes = append(notes, n)
+	}
+	active := !kept.Archived
+
+	for _, b := range d.Merged {
+		ids = append(ids, b.ID)
+
+		if kept.Description == "" {
+			kept.Description = b.Description
+		}
+		if kept.Metadata == nil {
+			kept.Metadata = b.Metadata
+		}
+		if kept.Icon == "" && b.Icon != "" {
+			kept.Icon, kept.Preview = b.Icon, b.Preview
+		}
+		if n := strings.TrimSpace(b.Notes); n != "" && !slices.Contains(notes, n) {
+			notes = append(notes, n)
+		}
+		if b.UpdatedAt.After(kept.UpdatedAt) {
+			kept.UpdatedAt = b.UpdatedAt
+		}
+		active = active || !b.Archived
+	}
+
+	query, args, err := sqlx.In(`
+        INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id)
+        SELECT ?, tag_id FROM bookmark_tags WHERE bookmark_id IN (?)
+    `, kept.ID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge bookmarks: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge bookmark tags: %w", err)
+	}
+
+	query, args, err = sqlx.In("DELETE FROM bookmarks WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge bookmarks: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete merged bookmarks: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE bookmarks SET
+            description = ?,
+            notes = ?,
+            metadata = ?,
+            icon = ?,
+            preview = ?,
+            updated_at = ?,
+            archived_at = iif(?, NULL, archived_at)
+        WHERE id = ?
+    `, kept.Description, strings.Join(notes, "\n\n"), kept.Metadata, kept.Icon, kept.Preview, kept.UpdatedAt, active, kept.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge bookmarks: %w", err)
+	}
+
+	return getBookmark(ctx, tx, kept.ID)
+}
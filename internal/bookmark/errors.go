@@ -41,7 +41,12 @@ type URLExistsError struct {
 }
 
 func (e *URLExistsError) Error() string {
-	return fmt.Sprintf("a bookmark with URL %s already exists: %s", e.URL, e.Err.Error())
+	msg := fmt.Sprintf("a bookmark with URL %s already exists", e.URL)
+	if e.Err != nil {
+		msg = msg + ": " + e.Err.Error()
+	}
+
+	return msg
 }
 
 func (e *URLExistsError) Unwrap() error {
@@ -52,3 +57,18 @@ func IsURLExists(err error) bool {
 	var u *URLExistsError
 	return errors.As(err, &u)
 }
+
+// ModifiedError is returned when a bookmark was changed after the patch
+// updating it was made.
+type ModifiedError struct {
+	ID int64
+}
+
+func (e *ModifiedError) Error() string {
+	return fmt.Sprintf("bookmark %d was modified since the update was made", e.ID)
+}
+
+func IsModified(err error) bool {
+	var m *ModifiedError
+	return errors.As(err, &m)
+}
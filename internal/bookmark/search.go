@@ -0,0 +1,44 @@
+package bookmark
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// SearchResult is a bookmark matched by a full-text search, along with
+// excerpts of the matched text. TitleHighlight and Snippet are escaped HTML
+// with each matched term wrapped in a <mark> element.
+type SearchResult struct {
+	Bookmark
+	TitleHighlight template.HTML `json:"titleHighlight" db:"title_highlight"`
+	Snippet        template.HTML `json:"snippet" db:"snippet"`
+}
+
+// Sentinel characters passed to the FTS5 highlight and snippet functions.
+// StripControl removes them from bookmark text before it's saved, so they
+// can be swapped for <mark> tags once the rest of the text has been escaped.
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+var highlightReplacer = strings.NewReplacer(
+	highlightStart, "<mark>",
+	highlightEnd, "</mark>",
+)
+
+// StripControl removes ASCII control characters other than tab, newline and
+// carriage return from s.
+func StripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func highlightHTML(s template.HTML) template.HTML {
+	return template.HTML(highlightReplacer.Replace(html.EscapeString(string(s))))
+}
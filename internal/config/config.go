@@ -2,17 +2,202 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/jobs"
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
 )
 
 type ServerConfig struct {
 	Host string `json:"host"`
 	Port uint   `json:"port"`
+	// QueryTimeout is how long a request may spend on database queries
+	// before it is cancelled, or 0 for no limit. Imports, exports, and
+	// backups aren't limited.
+	QueryTimeout Duration `json:"queryTimeout"`
+}
+
+type BackupConfig struct {
+	// Interval is how often the server backs up the database, or 0 to only
+	// back up on request. jobs.schedules can set a schedule instead.
+	Interval Duration `json:"interval"`
+	// KeepDaily is the number of days to keep the latest backup of.
+	KeepDaily int `json:"keepDaily"`
+	// KeepWeekly is the number of weeks to keep the latest backup of.
+	KeepWeekly int `json:"keepWeekly"`
+}
+
+type MetadataConfig struct {
+	// Timeout is how long fetching a page's metadata can take.
+	Timeout Duration `json:"timeout"`
+	// MaxBytes is how much of a page is read when looking for its metadata.
+	MaxBytes int64 `json:"maxBytes"`
+	// UserAgent is sent with requests for pages.
+	UserAgent string `json:"userAgent"`
+}
+
+type MediaConfig struct {
+	// Interval is how often the server looks for the icons and preview
+	// images of new bookmarks, or 0 to never look.
+	Interval Duration `json:"interval"`
+	// IconSize is the largest an icon is kept, in pixels on each side.
+	IconSize int `json:"iconSize"`
+	// PreviewWidth and PreviewHeight are the largest a preview image is
+	// kept, in pixels.
+	PreviewWidth  int `json:"previewWidth"`
+	PreviewHeight int `json:"previewHeight"`
+	// MaxBytes is the largest image that is downloaded.
+	MaxBytes int64 `json:"maxBytes"`
+	// Timeout is how long downloading an image can take.
+	Timeout Duration `json:"timeout"`
+}
+
+type LinkCheckConfig struct {
+	// Interval is how often the server looks for bookmarks whose links are
+	// due to be checked, or 0 to never check them.
+	Interval Duration `json:"interval"`
+	// Recheck is how long after a link was checked it is checked again.
+	Recheck Duration `json:"recheck"`
+	// HostDelay is the least time between requests to the same site.
+	HostDelay Duration `json:"hostDelay"`
+	// Timeout is how long a request for a page can take.
+	Timeout Duration `json:"timeout"`
+	// Workers is how many links are checked at once.
+	Workers int `json:"workers"`
+	// BrokenAfter is how many checks in a row must fail before a link is
+	// flagged as broken. Pages that are gone for good are flagged at once.
+	BrokenAfter int `json:"brokenAfter"`
+}
+
+type JobsConfig struct {
+	// Workers is how many background jobs run at once.
+	Workers int `json:"workers"`
+	// PollInterval is how often the server looks for jobs that are due.
+	PollInterval Duration `json:"pollInterval"`
+	// Backoff is how long a failed job waits before it is retried. The wait
+	// doubles with each failed attempt, up to MaxBackoff.
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxBackoff"`
+	// KeepDone is how long finished jobs are kept, or 0 to keep them
+	// forever. Jobs that failed for good are kept until they are retried.
+	KeepDone Duration `json:"keepDone"`
+	// Schedules replaces the interval of a recurring job with a schedule
+	// written like a crontab entry, like {"backup": "0 3 * * *"}. The
+	// recurring jobs are backup, media, and linkcheck.
+	Schedules map[string]string `json:"schedules"`
+}
+
+// RecurringJobs are the jobs that can be given a schedule in
+// jobs.schedules.
+var RecurringJobs = []string{"backup", "media", "linkcheck"}
+
+func (j *JobsConfig) validate() error {
+	if j.Workers < 1 {
+		return errors.New("jobs.workers must be at least 1")
+	}
+
+	if j.PollInterval <= 0 {
+		return errors.New("jobs.pollInterval must be more than 0")
+	}
+
+	for kind, spec := range j.Schedules {
+		if !slices.Contains(RecurringJobs, kind) {
+			return fmt.Errorf("jobs.schedules.%s: recurring jobs are %s", kind, strings.Join(RecurringJobs, ", "))
+		}
+
+		_, err := jobs.ParseSchedule(spec)
+		if err != nil {
+			return fmt.Errorf("jobs.schedules.%s: %w", kind, err)
+		}
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration written in config files as a string like
+// "24h" or "90m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+type DatabaseConfig struct {
+	// Path is the database file, relative to the data directory if it
+	// isn't absolute.
+	Path string `json:"path"`
+	// JournalMode is the SQLite journal mode. WAL lets reads carry on while
+	// a write is in progress.
+	JournalMode string `json:"journalMode"`
+	// Synchronous is how often SQLite waits for writes to reach the disk.
+	Synchronous string `json:"synchronous"`
+	// BusyTimeout is how long a connection waits for another to finish
+	// writing before failing with SQLITE_BUSY.
+	BusyTimeout Duration `json:"busyTimeout"`
+	// CacheSize is the page cache size per connection, in pages if positive
+	// or in KiB if negative, following PRAGMA cache_size.
+	CacheSize int `json:"cacheSize"`
+	// ForeignKeys enforces the foreign keys in the schema.
+	ForeignKeys bool `json:"foreignKeys"`
+	// MaxOpenConns limits the number of open connections, or 0 for no
+	// limit.
+	MaxOpenConns int `json:"maxOpenConns"`
+}
+
+var (
+	journalModes = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+	syncLevels   = []string{"off", "normal", "full", "extra"}
+)
+
+func (d *DatabaseConfig) validate() error {
+	if !slices.Contains(journalModes, strings.ToLower(d.JournalMode)) {
+		return fmt.Errorf("database.journalMode must be one of %s", strings.Join(journalModes, ", "))
+	}
+
+	if !slices.Contains(syncLevels, strings.ToLower(d.Synchronous)) {
+		return fmt.Errorf("database.synchronous must be one of %s", strings.Join(syncLevels, ", "))
+	}
+
+	if d.BusyTimeout < 0 || d.MaxOpenConns < 0 {
+		return errors.New("database.busyTimeout and database.maxOpenConns can't be negative")
+	}
+
+	return nil
 }
 
 type UserConfig struct {
-	Server *ServerConfig `json:"server"`
+	Server    *ServerConfig    `json:"server"`
+	Database  *DatabaseConfig  `json:"database"`
+	Tags      *tag.Rules       `json:"tags"`
+	URLs      *urlnorm.Rules   `json:"urls"`
+	Backup    *BackupConfig    `json:"backup"`
+	Metadata  *MetadataConfig  `json:"metadata"`
+	Media     *MediaConfig     `json:"media"`
+	LinkCheck *LinkCheckConfig `json:"linkCheck"`
+	Jobs      *JobsConfig      `json:"jobs"`
 }
 
 type UserDirs struct {
@@ -26,8 +211,43 @@ type Config struct {
 	UserConfig
 }
 
+func (c *Config) DBPath() string {
+	if filepath.IsAbs(c.Database.Path) {
+		return c.Database.Path
+	}
+
+	return filepath.Join(c.Dirs.DataHome, c.Database.Path)
+}
+
+// DBConnString returns the data source name to open the database with,
+// which sets the configured pragmas on every connection.
 func (c *Config) DBConnString() string {
-	return filepath.Join(c.Dirs.DataHome, "mnemonic.sqlite") + "?_time_format=sqlite"
+	d := c.Database
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", time.Duration(d.BusyTimeout).Milliseconds()))
+	q.Add("_pragma", fmt.Sprintf("journal_mode(%s)", strings.ToLower(d.JournalMode)))
+	q.Add("_pragma", fmt.Sprintf("synchronous(%s)", strings.ToLower(d.Synchronous)))
+	q.Add("_pragma", fmt.Sprintf("foreign_keys(%t)", d.ForeignKeys))
+	if d.CacheSize != 0 {
+		q.Add("_pragma", fmt.Sprintf("cache_size(%d)", d.CacheSize))
+	}
+	q.Set("_time_format", "sqlite")
+	// take the write lock when a transaction starts, so that waiting for
+	// another writer is covered by the busy timeout rather than failing
+	// when a read transaction tries to upgrade
+	q.Set("_txlock", "immediate")
+
+	// the path is escaped so that characters like ? and # stay part of it
+	u := &url.URL{Scheme: "file", Path: c.DBPath(), RawQuery: q.Encode()}
+	return u.String()
+}
+
+func (c *Config) BackupDir() string {
+	return filepath.Join(c.Dirs.DataHome, "backups")
+}
+
+func (c *Config) MediaDir() string {
+	return filepath.Join(c.Dirs.DataHome, "media")
 }
 
 type (
@@ -52,11 +272,98 @@ func getDataDir(lookup LookupEnv, fallback string) string {
 	return filepath.Join(fallback, "mnemonic")
 }
 
-var defaultConfig = &UserConfig{
-	Server: &ServerConfig{
-		Host: "127.0.0.1",
-		Port: 9753,
-	},
+// defaultConfig returns a new copy of the defaults each time, since a config
+// file is decoded over the top of them and only overrides the settings it
+// includes.
+func defaultConfig() UserConfig {
+	tags := tag.DefaultRules
+	urls := urlnorm.DefaultRules
+	urls.StripParams = slices.Clone(urls.StripParams)
+
+	return UserConfig{
+		Server: &ServerConfig{
+			Host:         "127.0.0.1",
+			Port:         9753,
+			QueryTimeout: Duration(10 * time.Second),
+		},
+		Database: &DatabaseConfig{
+			Path:         "mnemonic.sqlite",
+			JournalMode:  "wal",
+			Synchronous:  "normal",
+			BusyTimeout:  Duration(5 * time.Second),
+			ForeignKeys:  true,
+			MaxOpenConns: 0,
+		},
+		Tags: &tags,
+		URLs: &urls,
+		Backup: &BackupConfig{
+			Interval:   Duration(24 * time.Hour),
+			KeepDaily:  7,
+			KeepWeekly: 4,
+		},
+		Metadata: &MetadataConfig{
+			Timeout:   Duration(5 * time.Second),
+			MaxBytes:  2 << 20,
+			UserAgent: "mnemonic (+https://github.com/cmessinides/mnemonic)",
+		},
+		Media: &MediaConfig{
+			Interval:      Duration(time.Minute),
+			IconSize:      64,
+			PreviewWidth:  640,
+			PreviewHeight: 640,
+			MaxBytes:      5 << 20,
+			Timeout:       Duration(10 * time.Second),
+		},
+		LinkCheck: &LinkCheckConfig{
+			Interval:    Duration(time.Hour),
+			Recheck:     Duration(7 * 24 * time.Hour),
+			HostDelay:   Duration(2 * time.Second),
+			Timeout:     Duration(15 * time.Second),
+			Workers:     4,
+			BrokenAfter: 2,
+		},
+		Jobs: &JobsConfig{
+			Workers:      2,
+			PollInterval: Duration(5 * time.Second),
+			Backoff:      Duration(30 * time.Second),
+			MaxBackoff:   Duration(time.Hour),
+			KeepDone:     Duration(7 * 24 * time.Hour),
+			Schedules:    map[string]string{},
+		},
+	}
+}
+
+// fillDefaults puts back the default of every section set to null in the
+// config file.
+func fillDefaults(c *UserConfig) {
+	d := defaultConfig()
+	if c.Server == nil {
+		c.Server = d.Server
+	}
+	if c.Database == nil {
+		c.Database = d.Database
+	}
+	if c.Tags == nil {
+		c.Tags = d.Tags
+	}
+	if c.URLs == nil {
+		c.URLs = d.URLs
+	}
+	if c.Backup == nil {
+		c.Backup = d.Backup
+	}
+	if c.Metadata == nil {
+		c.Metadata = d.Metadata
+	}
+	if c.Media == nil {
+		c.Media = d.Media
+	}
+	if c.LinkCheck == nil {
+		c.LinkCheck = d.LinkCheck
+	}
+	if c.Jobs == nil {
+		c.Jobs = d.Jobs
+	}
 }
 
 func ReadConfig(
@@ -73,11 +380,9 @@ func ReadConfig(
 		DataHome:   getDataDir(lookup, dataHome),
 	}
 
-	var userConfig UserConfig
+	userConfig := defaultConfig()
 	configFile := filepath.Join(userDirs.ConfigHome, "config.json")
-	if !fileExists(configFile) {
-		userConfig = *defaultConfig
-	} else {
+	if fileExists(configFile) {
 		data, err := readFile(configFile)
 		if err != nil {
 			return nil, fmt.Errorf("could not read config file at %s: %w", configFile, err)
@@ -87,6 +392,18 @@ func ReadConfig(
 		if err != nil {
 			return nil, fmt.Errorf("could not parse config file at %s: %w", configFile, err)
 		}
+		fillDefaults(&userConfig)
+	}
+
+	err := userConfig.Database.validate()
+	if err == nil {
+		err = userConfig.URLs.Validate()
+	}
+	if err == nil {
+		err = userConfig.Jobs.validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file at %s: %w", configFile, err)
 	}
 
 	return &Config{
@@ -0,0 +1,82 @@
+// Package exporter writes bookmarks in formats other applications can
+// import.
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+)
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`
+
+const netscapeFooter = "</DL><p>\n"
+
+// NetscapeWriter writes bookmarks in the Netscape bookmark file format that
+// browsers import, as a flat list with each bookmark's tags in its TAGS
+// attribute and its description in a <DD>.
+type NetscapeWriter struct {
+	w           *bufio.Writer
+	wroteHeader bool
+}
+
+func NewNetscapeWriter(w io.Writer) *NetscapeWriter {
+	return &NetscapeWriter{w: bufio.NewWriter(w)}
+}
+
+func (nw *NetscapeWriter) Write(b *bookmark.Bookmark) error {
+	if !nw.wroteHeader {
+		nw.wroteHeader = true
+		nw.w.WriteString(netscapeHeader)
+	}
+
+	fmt.Fprintf(nw.w, `    <DT><A HREF="%s" ADD_DATE="%d" LAST_MODIFIED="%d"`,
+		html.EscapeString(b.URL), b.CreatedAt.Unix(), b.UpdatedAt.Unix())
+	if len(b.Tags) > 0 {
+		fmt.Fprintf(nw.w, ` TAGS="%s"`, html.EscapeString(strings.Join(b.Tags, ",")))
+	}
+	_, err := fmt.Fprintf(nw.w, ">%s</A>\n", html.EscapeString(b.Title))
+	if err == nil && b.Description != "" {
+		_, err = fmt.Fprintf(nw.w, "    <DD>%s\n", html.EscapeString(b.Description))
+	}
+
+	return err
+}
+
+// Close finishes the file and flushes it to the underlying writer. It
+// doesn't close the underlying writer.
+func (nw *NetscapeWriter) Close() error {
+	if !nw.wroteHeader {
+		nw.wroteHeader = true
+		nw.w.WriteString(netscapeHeader)
+	}
+	nw.w.WriteString(netscapeFooter)
+
+	return nw.w.Flush()
+}
+
+// ExportNetscape writes every bookmark matching filter to w as a Netscape
+// bookmark file.
+func ExportNetscape(ctx context.Context, w io.Writer, store bookmark.BookmarkStore, filter bookmark.Filter) error {
+	nw := NewNetscapeWriter(w)
+
+	err := store.Each(ctx, filter, nw.Write)
+	if err != nil {
+		return err
+	}
+
+	return nw.Close()
+}
@@ -0,0 +1,348 @@
+// Package importer brings bookmarks exported from other applications into
+// a bookmark store.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
+)
+
+// Item is a bookmark read from an export file.
+type Item struct {
+	Title string
+	URL   string
+	// Description is the note some formats keep with a bookmark. It becomes
+	// the bookmark's description, or its notes if it is too long for a
+	// description.
+	Description string
+	Tags        []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Archived    bool
+}
+
+// Format is a file format bookmarks can be imported from.
+type Format string
+
+const (
+	FormatNetscape Format = "netscape"
+	FormatPinboard Format = "pinboard"
+	FormatPocket   Format = "pocket"
+)
+
+// Parse reads items from a file in the given format.
+func Parse(format Format, r io.Reader) ([]*Item, error) {
+	switch format {
+	case FormatNetscape:
+		return ParseNetscape(r)
+	case FormatPinboard:
+		return ParsePinboard(r)
+	case FormatPocket:
+		return ParsePocket(r)
+	default:
+		return nil, fmt.Errorf("unknown import format %q (expected netscape, pinboard, or pocket)", format)
+	}
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatNetscape, FormatPinboard, FormatPocket:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown import format %q (expected netscape, pinboard, or pocket)", s)
+	}
+}
+
+// DuplicatePolicy decides what happens to an item whose URL is already
+// bookmarked.
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip leaves the existing bookmark alone.
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateMerge adds the item's tags to the existing bookmark.
+	DuplicateMerge DuplicatePolicy = "merge"
+	// DuplicateOverwrite replaces the existing bookmark with the item.
+	DuplicateOverwrite DuplicatePolicy = "overwrite"
+)
+
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(s); p {
+	case DuplicateSkip, DuplicateMerge, DuplicateOverwrite:
+		return p, nil
+	case "":
+		return DuplicateSkip, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q (expected skip, merge, or overwrite)", s)
+	}
+}
+
+// Report summarizes an import.
+type Report struct {
+	DryRun      bool         `json:"dryRun"`
+	Created     int          `json:"created"`
+	Skipped     int          `json:"skipped"`
+	Merged      int          `json:"merged"`
+	Overwritten int          `json:"overwritten"`
+	Failed      int          `json:"failed"`
+	Duplicates  []*Duplicate `json:"duplicates"`
+	Errors      []*ItemError `json:"errors"`
+	// Changes lists what would happen to each item, and is only filled in
+	// for a dry run.
+	Changes []*Change `json:"changes,omitempty"`
+}
+
+// Duplicate records an item whose URL was already bookmarked and what was
+// done about it.
+type Duplicate struct {
+	URL    string          `json:"url"`
+	Title  string          `json:"title"`
+	Action DuplicatePolicy `json:"action"`
+}
+
+// ItemError records an item that could not be imported.
+type ItemError struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Error string `json:"error"`
+}
+
+// ActionCreate is the action of a Change that adds a new bookmark. Changes
+// to existing bookmarks use the DuplicatePolicy that applies to them.
+const ActionCreate = "create"
+
+// Change describes what a dry run found an import would do to an item.
+type Change struct {
+	Action   string    `json:"action"`
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Tags     tag.Tags  `json:"tags"`
+	Archived bool      `json:"archived"`
+	Created  time.Time `json:"createdAt"`
+}
+
+type Options struct {
+	// Duplicates decides what happens to items that are already
+	// bookmarked.
+	Duplicates DuplicatePolicy
+	// DryRun reports what an import would do without changing anything.
+	DryRun bool
+	// TagRules are used to check tags during a dry run, and should match
+	// the rules of the store.
+	TagRules tag.Rules
+	// URLRules are used to spot duplicates within the import during a dry
+	// run, and should match the rules of the store.
+	URLRules urlnorm.Rules
+}
+
+type Importer struct {
+	store bookmark.BookmarkStore
+	opts  Options
+}
+
+func NewImporter(store bookmark.BookmarkStore, opts Options) *Importer {
+	if opts.Duplicates == "" {
+		opts.Duplicates = DuplicateSkip
+	}
+
+	return &Importer{
+		store: store,
+		opts:  opts,
+	}
+}
+
+// Import adds items to the store one at a time. Items that are invalid are
+// reported rather than stopping the import, but any other error stops it,
+// in which case the report covers the items imported so far.
+func (im *Importer) Import(ctx context.Context, items []*Item) (*Report, error) {
+	r := &Report{
+		DryRun:     im.opts.DryRun,
+		Duplicates: []*Duplicate{},
+		Errors:     []*ItemError{},
+	}
+
+	importItem := im.importItem
+	if im.opts.DryRun {
+		r.Changes = []*Change{}
+		importItem = im.planItem(map[string]bool{})
+	}
+
+	for _, item := range items {
+		err := importItem(ctx, r, item)
+		if err != nil {
+			var ie *tag.InvalidError
+			if !errors.As(err, &ie) {
+				return r, fmt.Errorf("failed to import %s: %w", item.URL, err)
+			}
+
+			r.fail(item, err)
+		}
+	}
+
+	return r, nil
+}
+
+func (im *Importer) importItem(ctx context.Context, r *Report, item *Item) error {
+	b, err := r.bookmark(item)
+	if b == nil {
+		return err
+	}
+
+	_, err = im.store.Import(ctx, b, false)
+	if err == nil {
+		r.Created++
+		return nil
+	}
+
+	var ue *bookmark.URLExistsError
+	if !errors.As(err, &ue) {
+		return err
+	}
+
+	r.duplicate(b, im.opts.Duplicates)
+
+	switch im.opts.Duplicates {
+	case DuplicateMerge:
+		existing, err := im.store.GetByURL(ctx, item.URL)
+		if err != nil {
+			return err
+		}
+
+		_, err = im.store.Update(ctx, bookmark.BookmarkPatch{ID: existing.ID, Tags: mergeTags(existing.Tags, b.Tags)})
+		if err != nil {
+			return err
+		}
+	case DuplicateOverwrite:
+		_, err = im.store.Import(ctx, b, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planItem returns a function that works out what importItem would do with
+// an item without changing the store. seen tracks the URL keys planned so
+// far, which would be bookmarked by the time later items are imported.
+func (im *Importer) planItem(seen map[string]bool) func(ctx context.Context, r *Report, item *Item) error {
+	return func(ctx context.Context, r *Report, item *Item) error {
+		b, err := r.bookmark(item)
+		if b == nil {
+			return err
+		}
+
+		b.Tags, err = im.opts.TagRules.Parse(b.Tags)
+		if err != nil {
+			return err
+		}
+
+		change := &Change{
+			Action:   ActionCreate,
+			URL:      b.URL,
+			Title:    b.Title,
+			Tags:     b.Tags,
+			Archived: b.Archived,
+			Created:  b.CreatedAt,
+		}
+
+		key := im.opts.URLRules.Key(b.URL)
+		existing, err := im.store.GetByURL(ctx, b.URL)
+		switch {
+		case err == nil || seen[key]:
+			change.Action = string(im.opts.Duplicates)
+			if im.opts.Duplicates != DuplicateOverwrite && existing != nil {
+				change.Title = existing.Title
+				change.Archived = existing.Archived
+				change.Created = existing.CreatedAt
+				change.Tags = existing.Tags
+			}
+			if im.opts.Duplicates == DuplicateMerge && existing != nil {
+				change.Tags = mergeTags(existing.Tags, b.Tags)
+			}
+
+			r.duplicate(b, im.opts.Duplicates)
+		case bookmark.IsNotFound(err):
+			r.Created++
+		default:
+			return err
+		}
+
+		seen[key] = true
+		r.Changes = append(r.Changes, change)
+		return nil
+	}
+}
+
+// bookmark checks an item and converts it to a bookmark, returning nil if
+// it was reported as failed or the store should not be touched.
+func (r *Report) bookmark(item *Item) (*bookmark.Bookmark, error) {
+	u, err := url.Parse(item.URL)
+	if err != nil || !u.IsAbs() {
+		r.fail(item, errors.New("not an absolute URL"))
+		return nil, nil
+	}
+
+	title := item.Title
+	if title == "" {
+		title = item.URL
+	}
+
+	description := strings.TrimSpace(item.Description)
+	var notes string
+	if utf8.RuneCountInString(description) > bookmark.MaxDescriptionLength {
+		description, notes = "", description
+	}
+
+	return &bookmark.Bookmark{
+		Title:       title,
+		URL:         item.URL,
+		Description: description,
+		Notes:       notes,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+		Archived:    item.Archived,
+		Tags:        item.Tags,
+	}, nil
+}
+
+func (r *Report) duplicate(b *bookmark.Bookmark, action DuplicatePolicy) {
+	r.Duplicates = append(r.Duplicates, &Duplicate{URL: b.URL, Title: b.Title, Action: action})
+
+	switch action {
+	case DuplicateMerge:
+		r.Merged++
+	case DuplicateOverwrite:
+		r.Overwritten++
+	default:
+		r.Skipped++
+	}
+}
+
+func (r *Report) fail(item *Item, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, &ItemError{URL: item.URL, Title: item.Title, Error: err.Error()})
+}
+
+// mergeTags adds the tags in b that aren't in a to the end of a.
+func mergeTags(a []string, b []string) tag.Tags {
+	tags := slices.Clone(tag.Tags(a))
+	for _, t := range b {
+		if !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"golang.org/x/net/html"
+)
+
+// ParseNetscape reads bookmarks in the Netscape bookmark file format
+// exported by browsers. Each bookmark is tagged with the path of the folder
+// it was in, so a bookmark in "Work" > "Infra" is tagged "Work/Infra", along
+// with any tags listed in its TAGS attribute. The <DD> after a bookmark is
+// its description.
+//
+// The browser's toolbar folder isn't used as a tag, since it says where a
+// bookmark was shown rather than what it is about.
+func ParseNetscape(r io.Reader) ([]*Item, error) {
+	items := []*Item{}
+	z := html.NewTokenizer(r)
+
+	// folders holds the name of each open <DL>, which is the name of the
+	// <H3> just before it, or "" when there is none
+	var folders []string
+	var heading *strings.Builder
+	var folder string
+	var item *Item
+	// last is the bookmark a <DD> would describe, and desc is the bookmark
+	// whose description is being read
+	var last, desc *Item
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return items, nil
+			}
+
+			return nil, fmt.Errorf("could not read bookmarks: %w", z.Err())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			// a description runs until the next tag other than a line break
+			if t.Data != "br" && t.Data != "p" {
+				desc = nil
+			}
+
+			switch t.Data {
+			case "dd":
+				desc, last = last, nil
+			case "h3":
+				last = nil
+				heading = &strings.Builder{}
+				if attr(t, "personal_toolbar_folder") == "true" {
+					// keep reading the heading, but don't use it
+					heading = nil
+				}
+				folder = ""
+			case "dl":
+				folders = append(folders, folder)
+				folder = ""
+			case "a":
+				last = nil
+				href := attr(t, "href")
+				// Firefox exports saved searches and other queries as place:
+				// links, which aren't bookmarks
+				if href == "" || strings.HasPrefix(href, "place:") {
+					continue
+				}
+
+				item = &Item{
+					URL:       href,
+					Tags:      folderTag(folders),
+					CreatedAt: parseUnixTime(attr(t, "add_date")),
+					UpdatedAt: parseUnixTime(attr(t, "last_modified")),
+				}
+				item.Tags = append(item.Tags, splitTags(attr(t, "tags"), ",")...)
+				items = append(items, item)
+				last = item
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h3":
+				if heading != nil {
+					folder = strings.TrimSpace(heading.String())
+				}
+				heading = nil
+			case "dl":
+				desc = nil
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case "a":
+				if item != nil {
+					item.Title = strings.TrimSpace(item.Title)
+				}
+				item = nil
+			}
+		case html.TextToken:
+			text := string(z.Text())
+			if heading != nil {
+				heading.WriteString(text)
+			} else if item != nil {
+				item.Title += text
+			} else if desc != nil {
+				desc.Description += text
+			}
+		}
+	}
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// folderTag returns the tag for a bookmark in the given folders, or nil if
+// it isn't in a named folder.
+func folderTag(folders []string) []string {
+	var path []string
+	for _, f := range folders {
+		if f != "" {
+			path = append(path, f)
+		}
+	}
+
+	if len(path) == 0 {
+		return nil
+	}
+
+	return []string{strings.Join(path, tag.Separator)}
+}
+
+// parseUnixTime parses a timestamp in seconds since the epoch, returning
+// the zero time if it is missing or invalid. Some browsers write
+// milliseconds or microseconds instead, which are detected by their size.
+func parseUnixTime(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+
+	switch {
+	case n > 1e15:
+		return time.UnixMicro(n)
+	case n > 1e12:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
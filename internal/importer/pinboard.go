@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PinboardToReadTag is added to bookmarks on Pinboard's to-read list, since
+// bookmarks have no unread state of their own.
+const PinboardToReadTag = "toread"
+
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+// ParsePinboard reads bookmarks from a Pinboard JSON export, which names the
+// title "description" and the description "extended".
+func ParsePinboard(r io.Reader) ([]*Item, error) {
+	var posts []*pinboardPost
+	err := json.NewDecoder(r).Decode(&posts)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Pinboard export: %w", err)
+	}
+
+	items := make([]*Item, 0, len(posts))
+	for _, p := range posts {
+		item := &Item{
+			Title:       strings.TrimSpace(p.Description),
+			URL:         p.Href,
+			Description: p.Extended,
+			Tags:        strings.Fields(p.Tags),
+		}
+
+		if t, err := time.Parse(time.RFC3339, p.Time); err == nil {
+			item.CreatedAt = t
+		}
+
+		if p.ToRead == "yes" {
+			item.Tags = append(item.Tags, PinboardToReadTag)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ParsePocket reads bookmarks from a Pocket export, which is either a CSV
+// file with title, url, time_added, tags, and status columns, or an HTML
+// page with a list of unread bookmarks followed by a list of archived ones.
+// Bookmarks Pocket has archived are archived.
+func ParsePocket(r io.Reader) ([]*Item, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not read Pocket export: %w", err)
+	}
+
+	start = bytes.TrimSpace(start)
+	if len(start) > 0 && start[0] == '<' {
+		return parsePocketHTML(br)
+	}
+
+	return parsePocketCSV(br)
+}
+
+func parsePocketCSV(r io.Reader) ([]*Item, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return []*Item{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read Pocket export: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("could not read Pocket export: missing url column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return record[i]
+	}
+
+	items := []*Item{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read Pocket export: %w", err)
+		}
+
+		items = append(items, &Item{
+			Title:     strings.TrimSpace(field(record, "title")),
+			URL:       field(record, "url"),
+			Tags:      splitTags(field(record, "tags"), "|"),
+			CreatedAt: parseUnixTime(field(record, "time_added")),
+			Archived:  field(record, "status") == "archive",
+		})
+	}
+}
+
+func parsePocketHTML(r io.Reader) ([]*Item, error) {
+	items := []*Item{}
+	z := html.NewTokenizer(r)
+
+	var heading *strings.Builder
+	var archived bool
+	var item *Item
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return items, nil
+			}
+
+			return nil, fmt.Errorf("could not read Pocket export: %w", z.Err())
+		case html.StartTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h1":
+				heading = &strings.Builder{}
+			case "a":
+				item = &Item{
+					URL:       attr(t, "href"),
+					Tags:      splitTags(attr(t, "tags"), ","),
+					CreatedAt: parseUnixTime(attr(t, "time_added")),
+					Archived:  archived,
+				}
+				items = append(items, item)
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h1":
+				// the export has an "Unread" list and a "Read Archive" list
+				if heading != nil {
+					archived = strings.Contains(strings.ToLower(heading.String()), "archive")
+				}
+				heading = nil
+			case "a":
+				if item != nil {
+					item.Title = strings.TrimSpace(item.Title)
+				}
+				item = nil
+			}
+		case html.TextToken:
+			if heading != nil {
+				heading.Write(z.Text())
+			} else if item != nil {
+				item.Title += string(z.Text())
+			}
+		}
+	}
+}
+
+func splitTags(s string, sep string) []string {
+	var tags []string
+	for _, name := range strings.Split(s, sep) {
+		if name = strings.TrimSpace(name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+
+	return tags
+}
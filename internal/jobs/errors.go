@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+)
+
+type NotFoundError struct {
+	ID  int64
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("no job found with id %d", e.ID)
+	if e.Err != nil {
+		msg = msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+func IsNotFound(err error) bool {
+	var n *NotFoundError
+	return errors.As(err, &n)
+}
+
+// NotDeadError is returned when retrying a job that hasn't failed for good.
+type NotDeadError struct {
+	ID     int64
+	Status Status
+}
+
+func (e *NotDeadError) Error() string {
+	return fmt.Sprintf("job %d is %s, and only dead jobs can be retried", e.ID, e.Status)
+}
+
+func IsNotDead(err error) bool {
+	var n *NotDeadError
+	return errors.As(err, &n)
+}
+
+// LeaseLostError is returned when a worker renews or finishes a job it no
+// longer holds the lease of, because the lease ran out and the job was
+// leased again or given up on.
+type LeaseLostError struct {
+	ID      int64
+	Attempt int
+}
+
+func (e *LeaseLostError) Error() string {
+	return fmt.Sprintf("lost the lease of job %d on attempt %d", e.ID, e.Attempt)
+}
+
+func IsLeaseLost(err error) bool {
+	var l *LeaseLostError
+	return errors.As(err, &l)
+}
+
+// ScheduleError is returned for a schedule that can't be parsed.
+type ScheduleError struct {
+	Spec   string
+	Reason string
+}
+
+func (e *ScheduleError) Error() string {
+	return fmt.Sprintf("invalid schedule %q: %s", e.Spec, e.Reason)
+}
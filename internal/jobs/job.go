@@ -0,0 +1,119 @@
+// Package jobs runs background work from a queue kept in the database, so
+// that work survives restarts and failed work is retried. Jobs can be
+// enqueued as they come up or on a recurring schedule.
+package jobs
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Status is where a job is in the queue.
+type Status string
+
+const (
+	// StatusPending jobs are waiting to run at RunAt, either for the first
+	// time or to be retried.
+	StatusPending Status = "pending"
+	// StatusRunning jobs are leased by a worker.
+	StatusRunning Status = "running"
+	// StatusDone jobs succeeded.
+	StatusDone Status = "done"
+	// StatusDead jobs failed every attempt and won't run again unless they
+	// are retried by hand.
+	StatusDead Status = "dead"
+)
+
+func ParseStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case StatusPending, StatusRunning, StatusDone, StatusDead:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown job status %q", s)
+	}
+}
+
+type Job struct {
+	ID int64 `json:"id"`
+	// Kind decides which handler runs the job.
+	Kind    string  `json:"kind"`
+	Payload Payload `json:"payload"`
+	Status  Status  `json:"status"`
+	// Attempts is how many times the job has been started.
+	Attempts    int `json:"attempts"`
+	MaxAttempts int `json:"maxAttempts" db:"max_attempts"`
+	// RunAt is when the job can next be started.
+	RunAt time.Time `json:"runAt" db:"run_at"`
+	// LeasedUntil is when a running job is given up on if its worker stops
+	// renewing the lease.
+	LeasedUntil *time.Time `json:"leasedUntil,omitempty" db:"leased_until"`
+	// LastError is why the latest attempt failed.
+	LastError  string     `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+}
+
+// Decode unmarshals the job's payload into v.
+func (j *Job) Decode(v any) error {
+	err := json.Unmarshal(j.Payload, v)
+	if err != nil {
+		return fmt.Errorf("could not decode payload of %s job %d: %w", j.Kind, j.ID, err)
+	}
+
+	return nil
+}
+
+// JobInit is a job to enqueue.
+type JobInit struct {
+	Kind string
+	// Payload is marshalled to JSON for the handler to Decode.
+	Payload any
+	// RunAt is when the job can first run. The zero time runs it as soon as
+	// possible.
+	RunAt time.Time
+	// MaxAttempts is how many times the job is tried before it is dead. 0
+	// uses the queue's default.
+	MaxAttempts int
+}
+
+// Payload is the JSON a job was enqueued with.
+type Payload []byte
+
+func (p Payload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+
+	return p, nil
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	*p = append((*p)[:0], data...)
+	return nil
+}
+
+func (p *Payload) Scan(src any) error {
+	switch s := src.(type) {
+	case []byte:
+		*p = append(Payload(nil), s...)
+	case string:
+		*p = Payload(s)
+	case nil:
+		*p = nil
+	default:
+		return fmt.Errorf("unable to parse payload: cannot handle value of type %T", s)
+	}
+
+	return nil
+}
+
+func (p Payload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return "null", nil
+	}
+
+	return string(p), nil
+}
@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Handler does the work of a job. A job whose handler returns an error is
+// retried later.
+type Handler func(ctx context.Context, job *Job) error
+
+type Options struct {
+	// Workers is how many jobs run at once.
+	Workers int
+	// PollInterval is how often idle workers look for due jobs, and how
+	// often schedules are checked.
+	PollInterval time.Duration
+	// Lease is how long a job is left running after its worker stops
+	// renewing it before another worker picks it up.
+	Lease time.Duration
+	// Backoff is how long a job waits to be retried after its first failed
+	// attempt. It doubles with each attempt after that, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// KeepDone is how long jobs that are done are kept, or 0 to keep them
+	// forever.
+	KeepDone time.Duration
+}
+
+type recurring struct {
+	kind     string
+	schedule Schedule
+}
+
+// Runner runs the jobs in a queue with a pool of workers, and enqueues
+// recurring jobs as their schedules come due.
+type Runner struct {
+	store     Store
+	opts      Options
+	handlers  map[string]Handler
+	recurring []recurring
+	// wake tells an idle worker that a job was just enqueued
+	wake chan struct{}
+}
+
+func NewRunner(store Store, opts Options) *Runner {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 5 * time.Minute
+	}
+
+	return &Runner{
+		store:    store,
+		opts:     opts,
+		handlers: map[string]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Handle makes h run jobs of the kind. It must be called before Run.
+func (r *Runner) Handle(kind string, h Handler) {
+	r.handlers[kind] = h
+}
+
+// Recur enqueues a job of the kind whenever the schedule is due. It must be
+// called before Run.
+func (r *Runner) Recur(kind string, schedule Schedule) {
+	r.recurring = append(r.recurring, recurring{kind: kind, schedule: schedule})
+}
+
+// Enqueue adds a job to the queue and wakes a worker to run it.
+func (r *Runner) Enqueue(ctx context.Context, init JobInit) (*Job, error) {
+	job, err := r.store.Enqueue(ctx, init)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Run runs jobs until ctx is done. Only jobs of kinds with a handler are
+// run, so other kinds wait in the queue.
+func (r *Runner) Run(ctx context.Context) {
+	kinds := slices.Sorted(maps.Keys(r.handlers))
+
+	var wg sync.WaitGroup
+	for range r.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, kinds)
+		}()
+	}
+
+	r.schedule(ctx)
+	wg.Wait()
+}
+
+// schedule enqueues recurring jobs that are due and prunes old jobs every
+// PollInterval.
+func (r *Runner) schedule(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		for _, rec := range r.recurring {
+			job, err := r.store.EnqueueScheduled(ctx, rec.kind, rec.schedule, now)
+			if err != nil {
+				log.Println(err)
+			} else if job != nil {
+				select {
+				case r.wake <- struct{}{}:
+				default:
+				}
+			}
+		}
+
+		if r.opts.KeepDone > 0 {
+			_, err := r.store.Prune(ctx, now.Add(-r.opts.KeepDone))
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work runs due jobs one at a time, waiting for more when there are none.
+func (r *Runner) work(ctx context.Context, kinds []string) {
+	for {
+		job, err := r.store.Lease(ctx, kinds, r.opts.Lease)
+		if err != nil {
+			log.Println(err)
+		}
+		if job != nil {
+			r.runJob(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// runJob runs a leased job, renewing its lease until its handler returns,
+// and records how it went.
+func (r *Runner) runJob(ctx context.Context, job *Job) {
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	defer stopRenewing()
+	go r.renew(renewCtx, job)
+
+	err := r.call(ctx, job)
+	stopRenewing()
+
+	// the outcome is recorded even if the runner is stopping, so that a
+	// finished job isn't run again
+	recordCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		err = r.store.Complete(recordCtx, job.ID, job.Attempts)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	failed, ferr := r.store.Fail(recordCtx, job.ID, job.Attempts, err, time.Now().Add(r.backoff(job.Attempts)))
+	if ferr != nil {
+		log.Println(ferr)
+		return
+	}
+
+	if failed.Status == StatusDead {
+		log.Printf("%s job %d failed for good after %d attempts: %v", job.Kind, job.ID, failed.Attempts, err)
+	} else {
+		log.Printf("%s job %d failed, retrying at %s: %v", job.Kind, job.ID, failed.RunAt.Format(time.RFC3339), err)
+	}
+}
+
+// call runs a job's handler, turning a panic into an error so that one bad
+// job doesn't stop the server.
+func (r *Runner) call(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return r.handlers[job.Kind](ctx, job)
+}
+
+func (r *Runner) renew(ctx context.Context, job *Job) {
+	ticker := time.NewTicker(r.opts.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.store.Renew(ctx, job.ID, job.Attempts, r.opts.Lease)
+			if err != nil && ctx.Err() == nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// backoff returns how long to wait before retrying a job that has failed
+// attempts times.
+func (r *Runner) backoff(attempts int) time.Duration {
+	d := r.opts.Backoff
+	for i := 1; i < attempts && (r.opts.MaxBackoff <= 0 || d < r.opts.MaxBackoff); i++ {
+		d *= 2
+	}
+	if r.opts.MaxBackoff > 0 {
+		d = min(d, r.opts.MaxBackoff)
+	}
+
+	return d
+}
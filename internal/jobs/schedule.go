@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a recurring job runs.
+type Schedule interface {
+	// Next returns the first time after t that the job is due, or the zero
+	// time if it never is.
+	Next(t time.Time) time.Time
+	// String returns the schedule as ParseSchedule reads it.
+	String() string
+}
+
+// Every returns a schedule that is due every d.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func (e every) String() string {
+	return "@every " + time.Duration(e).String()
+}
+
+var shorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule reads a schedule written like a crontab entry, as the five
+// fields minute, hour, day of month, month, and day of week, in local time.
+// Each field is "*", a number, a range like "1-5", or a list of them like
+// "1,15", and "*" and ranges can take a step like "*/15". Days of the week
+// count from 0 for Sunday, and 7 is Sunday too. As with cron, a day matches
+// if it matches either of the day fields when both are given. The
+// shorthands "@hourly", "@daily", "@weekly", "@monthly", and "@every 90m"
+// work too.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, &ScheduleError{Spec: spec, Reason: err.Error()}
+		}
+		if interval < time.Second {
+			return nil, &ScheduleError{Spec: spec, Reason: "the interval must be at least a second"}
+		}
+		return Every(interval), nil
+	}
+
+	expanded := spec
+	if s, ok := shorthands[spec]; ok {
+		expanded = s
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, &ScheduleError{Spec: spec, Reason: "expected 5 fields"}
+	}
+
+	c := &cron{spec: spec}
+	var err error
+	c.minute, err = parseField(fields[0], 0, 59)
+	if err == nil {
+		c.hour, err = parseField(fields[1], 0, 23)
+	}
+	if err == nil {
+		c.dom, err = parseField(fields[2], 1, 31)
+	}
+	if err == nil {
+		c.month, err = parseField(fields[3], 1, 12)
+	}
+	if err == nil {
+		c.dow, err = parseField(fields[4], 0, 7)
+	}
+	if err != nil {
+		return nil, &ScheduleError{Spec: spec, Reason: err.Error()}
+	}
+
+	// 7 is another way to write Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDOM = strings.HasPrefix(fields[2], "*")
+	c.anyDOW = strings.HasPrefix(fields[4], "*")
+	if c.Next(time.Now()).IsZero() {
+		return nil, &ScheduleError{Spec: spec, Reason: "it is never due"}
+	}
+
+	return c, nil
+}
+
+// parseField reads a cron field into a set of bits, one for each value.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step %q in %s", stepStr, field)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			lo, err = strconv.Atoi(loStr)
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q in %s", loStr, field)
+			}
+			hi = lo
+			if isRange {
+				hi, err = strconv.Atoi(hiStr)
+				if err != nil {
+					return 0, fmt.Errorf("bad value %q in %s", hiStr, field)
+				}
+			} else if hasStep {
+				// like cron, "5/10" means from 5 to the end in steps of 10
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("values in %s must be from %d to %d", field, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+type cron struct {
+	spec                     string
+	minute, hour, dom, month uint64
+	dow                      uint64
+	anyDOM, anyDOW           bool
+}
+
+// maxSearch is how far ahead Next looks before deciding a schedule is never
+// due, like one set for the 31st of February.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+func (c *cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// start at the next whole minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		y, mo, d := t.Date()
+		switch {
+		case c.month&(1<<int(mo)) == 0:
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(y, mo, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (c *cron) String() string {
+	return c.spec
+}
@@ -0,0 +1,401 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/pagination"
+	"github.com/cmessinides/mnemonic/internal/sqltime"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultMaxAttempts is how many times a job is tried when it is enqueued
+// without saying.
+const DefaultMaxAttempts = 5
+
+// Filter limits the jobs that are listed. Empty fields match any job.
+type Filter struct {
+	Status Status
+	Kind   string
+}
+
+type Store interface {
+	Enqueue(ctx context.Context, init JobInit) (*Job, error)
+	EnqueueScheduled(ctx context.Context, kind string, schedule Schedule, now time.Time) (*Job, error)
+	Lease(ctx context.Context, kinds []string, lease time.Duration) (*Job, error)
+	Renew(ctx context.Context, id int64, attempt int, lease time.Duration) error
+	Complete(ctx context.Context, id int64, attempt int) error
+	Fail(ctx context.Context, id int64, attempt int, jobErr error, retryAt time.Time) (*Job, error)
+	Get(ctx context.Context, id int64) (*Job, error)
+	GetPage(ctx context.Context, page uint64, pageSize uint64, filter Filter) (*pagination.Page[*Job], error)
+	Retry(ctx context.Context, id int64) (*Job, error)
+	Prune(ctx context.Context, finishedBefore time.Time) (int64, error)
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{
+		db: sqlx.NewDb(db, "sqlite"),
+	}
+}
+
+type SQLiteStore struct {
+	db *sqlx.DB
+}
+
+func (s *SQLiteStore) Enqueue(ctx context.Context, init JobInit) (*Job, error) {
+	return enqueue(ctx, s.db, init)
+}
+
+func enqueue(ctx context.Context, q sqlx.QueryerContext, init JobInit) (*Job, error) {
+	payload, err := json.Marshal(init.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode payload of %s job: %w", init.Kind, err)
+	}
+
+	now := time.Now()
+	runAt := init.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+	maxAttempts := init.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	job := &Job{}
+	err = sqlx.GetContext(ctx, q, job, `
+        INSERT INTO jobs (kind, payload, status, max_attempts, run_at, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        RETURNING *
+    `, init.Kind, string(payload), StatusPending, maxAttempts, runAt, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue %s job: %w", init.Kind, err)
+	}
+
+	return job, nil
+}
+
+// EnqueueScheduled enqueues a job of the kind if its schedule is due at
+// now, and works out when it is next due. A kind that has never been
+// scheduled is due straight away, and one whose schedule has changed is
+// next due by the new schedule. No job is enqueued while the last one of
+// the kind is still pending or running, so a slow job doesn't pile up
+// behind itself. It returns the new job, or nil if there isn't one.
+func (s *SQLiteStore) EnqueueScheduled(ctx context.Context, kind string, schedule Schedule, now time.Time) (*Job, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule %s job: %w", kind, err)
+	}
+	defer tx.Rollback()
+
+	var current struct {
+		Spec string
+		Due  bool
+	}
+	err = tx.GetContext(ctx, &current, `
+        SELECT spec, julianday(next_run_at) <= julianday(?) AS due
+        FROM job_schedules
+        WHERE kind = ?
+    `, sqltime.Julian(now), kind)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("could not select schedule of %s jobs: %w", kind, err)
+	}
+
+	known := err == nil
+	due := !known || (current.Spec == schedule.String() && current.Due)
+	if known && !due && current.Spec == schedule.String() {
+		return nil, nil
+	}
+
+	var job *Job
+	if due {
+		var waiting bool
+		err = tx.GetContext(ctx, &waiting, `
+            SELECT EXISTS (SELECT 1 FROM jobs WHERE kind = ? AND status IN (?, ?))
+        `, kind, StatusPending, StatusRunning)
+		if err != nil {
+			return nil, fmt.Errorf("could not select %s jobs: %w", kind, err)
+		}
+
+		if !waiting {
+			job, err = enqueue(ctx, tx, JobInit{Kind: kind, RunAt: now})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	next := schedule.Next(now)
+	if next.IsZero() {
+		// the schedule is never due again
+		next = now.Add(maxSearch)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO job_schedules (kind, spec, next_run_at)
+        VALUES (?, ?, ?)
+        ON CONFLICT (kind) DO UPDATE SET spec = excluded.spec, next_run_at = excluded.next_run_at
+    `, kind, schedule.String(), next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update schedule of %s jobs: %w", kind, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule %s job: %w", kind, err)
+	}
+
+	return job, nil
+}
+
+// Lease starts the next job of one of the kinds that is due, marking it
+// running until the lease runs out, and returns it, or nil if no job is
+// due. Running jobs whose lease has run out are due again, since their
+// worker must have stopped, unless they have used up their attempts, in
+// which case they are dead. The job's Attempts is the lease's token, which
+// Renew, Complete, and Fail must be given so that a worker that has lost
+// its lease can't change the job under the worker that took it over.
+func (s *SQLiteStore) Lease(ctx context.Context, kinds []string, lease time.Duration) (*Job, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+        UPDATE jobs SET
+            status = ?,
+            leased_until = NULL,
+            last_error = 'the worker stopped before the job finished',
+            updated_at = ?,
+            finished_at = ?
+        WHERE status = ? AND julianday(leased_until) < julianday(?) AND attempts >= max_attempts
+    `, StatusDead, now, now, StatusRunning, sqltime.Julian(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire jobs: %w", err)
+	}
+
+	query, args, err := sqlx.In(`
+        UPDATE jobs SET
+            status = ?,
+            attempts = attempts + 1,
+            leased_until = ?,
+            updated_at = ?
+        WHERE id = (
+            SELECT id
+            FROM jobs
+            WHERE kind IN (?) AND (
+                (status = ? AND julianday(run_at) <= julianday(?)) OR
+                (status = ? AND julianday(leased_until) < julianday(?))
+            )
+            ORDER BY run_at, id
+            LIMIT 1
+        )
+        RETURNING *
+    `, StatusRunning, now.Add(lease), now, kinds, StatusPending, sqltime.Julian(now), StatusRunning, sqltime.Julian(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+
+	job := &Job{}
+	err = tx.GetContext(ctx, job, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the expired jobs are still marked dead
+		job = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Renew extends the lease of a running job.
+func (s *SQLiteStore) Renew(ctx context.Context, id int64, attempt int, lease time.Duration) error {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE jobs SET leased_until = ?, updated_at = ? WHERE id = ? AND status = ? AND attempts = ?
+    `, now.Add(lease), now, id, StatusRunning, attempt)
+	if err != nil {
+		return fmt.Errorf("failed to renew lease of job %d: %w", id, err)
+	}
+
+	return expectLease(res, id, attempt)
+}
+
+// Complete marks a running job done.
+func (s *SQLiteStore) Complete(ctx context.Context, id int64, attempt int) error {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE jobs SET
+            status = ?,
+            leased_until = NULL,
+            last_error = '',
+            updated_at = ?,
+            finished_at = ?
+        WHERE id = ? AND status = ? AND attempts = ?
+    `, StatusDone, now, now, id, StatusRunning, attempt)
+	if err != nil {
+		return fmt.Errorf("failed to complete job %d: %w", id, err)
+	}
+
+	return expectLease(res, id, attempt)
+}
+
+// Fail records why a running job failed. The job is pending again until
+// retryAt if it has attempts left, and dead if it hasn't.
+func (s *SQLiteStore) Fail(ctx context.Context, id int64, attempt int, jobErr error, retryAt time.Time) (*Job, error) {
+	now := time.Now()
+	job := &Job{}
+	err := s.db.GetContext(ctx, job, `
+        UPDATE jobs SET
+            status = iif(attempts >= max_attempts, ?, ?),
+            run_at = iif(attempts >= max_attempts, run_at, ?),
+            leased_until = NULL,
+            last_error = ?,
+            updated_at = ?,
+            finished_at = iif(attempts >= max_attempts, ?, NULL)
+        WHERE id = ? AND status = ? AND attempts = ?
+        RETURNING *
+    `, StatusDead, StatusPending, retryAt, jobErr.Error(), now, now, id, StatusRunning, attempt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &LeaseLostError{ID: id, Attempt: attempt}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failure of job %d: %w", id, err)
+	}
+
+	return job, nil
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id int64) (*Job, error) {
+	job := &Job{}
+	err := s.db.GetContext(ctx, job, "SELECT * FROM jobs WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &NotFoundError{ID: id, Err: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not select job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetPage returns a page of the jobs matching filter, newest first.
+func (s *SQLiteStore) GetPage(ctx context.Context, page uint64, pageSize uint64, filter Filter) (*pagination.Page[*Job], error) {
+	where := "1 = 1"
+	args := []any{}
+	if filter.Status != "" {
+		where += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.Kind != "" {
+		where += " AND kind = ?"
+		args = append(args, filter.Kind)
+	}
+
+	jobs := []*Job{}
+	err := s.db.SelectContext(ctx, &jobs, `
+        SELECT *
+        FROM jobs
+        WHERE `+where+`
+        ORDER BY id DESC
+        LIMIT ? OFFSET ?
+    `, append(args, pageSize, pagination.Offset(page, pageSize))...)
+	if err != nil {
+		return nil, fmt.Errorf("could not select jobs: %w", err)
+	}
+
+	var total uint64
+	err = s.db.GetContext(ctx, &total, "SELECT COUNT(1) FROM jobs WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not select job total: %w", err)
+	}
+
+	return pagination.NewPage(jobs, page, pageSize, total), nil
+}
+
+// Retry makes a dead job pending again, with all of its attempts, to run
+// straight away.
+func (s *SQLiteStore) Retry(ctx context.Context, id int64) (*Job, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job %d: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	var status Status
+	err = tx.GetContext(ctx, &status, "SELECT status FROM jobs WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &NotFoundError{ID: id, Err: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not select job: %w", err)
+	}
+	if status != StatusDead {
+		return nil, &NotDeadError{ID: id, Status: status}
+	}
+
+	now := time.Now()
+	job := &Job{}
+	err = tx.GetContext(ctx, job, `
+        UPDATE jobs SET
+            status = ?,
+            attempts = 0,
+            run_at = ?,
+            updated_at = ?,
+            finished_at = NULL
+        WHERE id = ?
+        RETURNING *
+    `, StatusPending, now, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job %d: %w", id, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job %d: %w", id, err)
+	}
+
+	return job, nil
+}
+
+// Prune deletes jobs that were done before finishedBefore and returns how
+// many it deleted. Dead jobs are kept until they are retried.
+func (s *SQLiteStore) Prune(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+        DELETE FROM jobs WHERE status = ? AND julianday(finished_at) < julianday(?)
+    `, StatusDone, sqltime.Julian(finishedBefore))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune jobs: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// expectLease returns a *LeaseLostError if res didn't change a row, which
+// means the job is missing, isn't running any more, or was leased again.
+func expectLease(res sql.Result, id int64, attempt int) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &LeaseLostError{ID: id, Attempt: attempt}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package linkcheck checks whether bookmarked pages still exist, keeping a
+// history of what each check found.
+package linkcheck
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+)
+
+// Check is the outcome of one request for a bookmarked page.
+type Check struct {
+	ID         int64     `json:"id"`
+	BookmarkID int64     `json:"bookmarkId" db:"bookmark_id"`
+	CheckedAt  time.Time `json:"checkedAt" db:"checked_at"`
+	// Status is the HTTP status of the response, or nil if there was no
+	// response, in which case Error says why.
+	Status *int `json:"status"`
+	// FinalURL is where the page was found after following redirects.
+	FinalURL  string `json:"finalUrl" db:"final_url"`
+	LatencyMS int64  `json:"latencyMs" db:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	// URL is the bookmark's URL that was checked, and Redirected is set
+	// when FinalURL is a different page from it. They are only known while
+	// checking, and aren't stored.
+	URL        string `json:"-" db:"-"`
+	Redirected bool   `json:"-" db:"-"`
+}
+
+// Failed reports whether the check found the page missing or unreachable.
+// Responses that usually mean a site turned the checker away rather than
+// that the page is gone don't count.
+func (c *Check) Failed() bool {
+	if c.Status == nil {
+		return true
+	}
+
+	return !c.turnedAway() && *c.Status >= 400
+}
+
+// turnedAway reports whether the site refused the checker, which says
+// nothing about whether the page is still there.
+func (c *Check) turnedAway() bool {
+	if c.Status == nil {
+		return false
+	}
+
+	switch *c.Status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
+
+// gone reports whether the check found the page missing in a way that
+// isn't likely to change.
+func (c *Check) gone() bool {
+	return c.Status != nil && (*c.Status == http.StatusNotFound || *c.Status == http.StatusGone)
+}
+
+// Summarize decides a bookmark's link status from its most recent checks,
+// newest first, and whether the newest was redirected to another page. A
+// page is broken if it is gone or the last brokenAfter checks all failed,
+// so that a site being down for a while doesn't flag it. A failed check
+// that doesn't make it broken leaves the current status as it is, and so
+// does a check the site turned away.
+func Summarize(current bookmark.LinkStatus, redirected bool, recent []*Check, brokenAfter int) bookmark.LinkStatus {
+	if len(recent) == 0 {
+		return current
+	}
+
+	latest := recent[0]
+	if latest.turnedAway() {
+		return current
+	}
+	if !latest.Failed() {
+		if redirected {
+			return bookmark.LinkRedirected
+		}
+		return bookmark.LinkOK
+	}
+
+	if latest.gone() {
+		return bookmark.LinkBroken
+	}
+
+	brokenAfter = max(brokenAfter, 1)
+	if len(recent) < brokenAfter {
+		return current
+	}
+	for _, c := range recent[:brokenAfter] {
+		if !c.Failed() {
+			return current
+		}
+	}
+
+	return bookmark.LinkBroken
+}
@@ -0,0 +1,254 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
+)
+
+type Options struct {
+	// Recheck is how long after a page was last checked it is checked
+	// again.
+	Recheck time.Duration
+	// HostDelay is the least time between requests to the same host.
+	HostDelay time.Duration
+	// Timeout limits how long checking one page can take.
+	Timeout time.Duration
+	// Workers is how many pages are checked at once.
+	Workers int
+	// BrokenAfter is how many checks in a row must fail before a page that
+	// isn't gone for good is flagged as broken.
+	BrokenAfter int
+	// UserAgent is sent with each check. Sites that turn unfamiliar agents
+	// away with 403 don't get the page flagged, since that isn't counted as
+	// a failure.
+	UserAgent string
+	// URLRules decide whether a redirect leads to a different page.
+	URLRules urlnorm.Rules
+}
+
+// batchSize is how many due bookmarks are taken from the store at a time,
+// so that a long backlog of checks isn't held in memory.
+const batchSize = 100
+
+// maxRedirects matches the limit of the default HTTP client.
+const maxRedirects = 10
+
+// Checker checks bookmarked pages that are due to be checked and records
+// what it finds.
+type Checker struct {
+	store   Store
+	client  *http.Client
+	opts    Options
+	limiter *hostLimiter
+}
+
+// NewChecker returns a checker that makes requests with a copy of client,
+// or of http.DefaultClient if client is nil. Redirects count towards the
+// per-host rate limit too.
+func NewChecker(store Store, client *http.Client, opts Options) *Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	limiter := &hostLimiter{delay: opts.HostDelay, next: map[string]time.Time{}}
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("stopped after 10 redirects")
+		}
+		return limiter.wait(req.Context(), req.URL.Host)
+	}
+
+	return &Checker{
+		store:   store,
+		client:  &c,
+		opts:    opts,
+		limiter: limiter,
+	}
+}
+
+// Run checks every page that is due and returns how many it checked. The
+// pages are checked by several workers at once, but never more often than
+// HostDelay on any one host.
+func (c *Checker) Run(ctx context.Context) (int, error) {
+	// anything checked during this run was checked after this, so it won't
+	// be found due again
+	checkedBefore := time.Now().Add(-c.opts.Recheck)
+	checked := 0
+
+	for {
+		due, err := c.store.Due(ctx, checkedBefore, batchSize)
+		if err != nil {
+			return checked, err
+		}
+		if len(due) == 0 {
+			return checked, nil
+		}
+
+		targets := make(chan *Target)
+		results := make(chan *Check)
+		var wg sync.WaitGroup
+		for range c.opts.Workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for t := range targets {
+					results <- c.Check(ctx, t)
+				}
+			}()
+		}
+		go func() {
+			for _, t := range due {
+				targets <- t
+			}
+			close(targets)
+			wg.Wait()
+			close(results)
+		}()
+
+		var recordErr error
+		for check := range results {
+			if recordErr != nil {
+				continue
+			}
+
+			// a bookmark deleted or given a new URL while it was being
+			// checked has nothing to record
+			_, err = c.store.Record(ctx, check, c.opts.BrokenAfter)
+			if bookmark.IsNotFound(err) || IsURLChanged(err) {
+				continue
+			}
+			if err != nil {
+				recordErr = err
+				continue
+			}
+			checked++
+		}
+		if recordErr != nil {
+			return checked, recordErr
+		}
+	}
+}
+
+// Check requests a page and reports what happened. It tries a HEAD request
+// first, which doesn't download the page, and falls back to GET for
+// servers that don't handle HEAD properly.
+func (c *Checker) Check(ctx context.Context, t *Target) *Check {
+	check := &Check{BookmarkID: t.ID, URL: t.URL, CheckedAt: time.Now()}
+
+	res, latency, err := c.request(ctx, http.MethodHead, t.URL)
+	if err != nil || res.StatusCode >= 400 {
+		res, latency, err = c.request(ctx, http.MethodGet, t.URL)
+	}
+
+	check.LatencyMS = latency.Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	status := res.StatusCode
+	check.Status = &status
+	check.FinalURL = res.Request.URL.String()
+	check.Redirected = c.opts.URLRules.Key(check.FinalURL) != c.opts.URLRules.Key(t.URL)
+
+	return check
+}
+
+// request makes a request and closes the response, returning how long the
+// response took to start arriving. The timeout starts once the rate limit
+// allows the request, so waiting for a busy host doesn't use it up.
+func (c *Checker) request(ctx context.Context, method string, u string) (*http.Response, time.Duration, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, 0, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, 0, errors.New("only http and https links can be checked")
+	}
+
+	err = c.limiter.wait(ctx, parsed.Host)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+
+	start := time.Now()
+	res, err := c.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, latency, err
+	}
+
+	// a little of the body is read so that the connection can be reused,
+	// but not the whole page
+	io.CopyN(io.Discard, res.Body, 4096)
+	res.Body.Close()
+
+	return res, latency, nil
+}
+
+// hostLimiter spaces out requests to each host.
+type hostLimiter struct {
+	delay time.Duration
+	mu    sync.Mutex
+	// next is the earliest time of the next request to each host
+	next map[string]time.Time
+}
+
+// wait blocks until a request can be made to host, reserving the slot.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.delay <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.delay)
+
+	// forget hosts that are free again, so the map doesn't keep growing
+	for h, t := range l.next {
+		if t.Before(now) {
+			delete(l.next, h)
+		}
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
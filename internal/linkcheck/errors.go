@@ -0,0 +1,22 @@
+package linkcheck
+
+import (
+	"errors"
+	"fmt"
+)
+
+// URLChangedError is returned when recording a check of a bookmark whose
+// URL was changed while its old one was being checked.
+type URLChangedError struct {
+	BookmarkID int64
+	URL        string
+}
+
+func (e *URLChangedError) Error() string {
+	return fmt.Sprintf("bookmark %d no longer has the URL %s that was checked", e.BookmarkID, e.URL)
+}
+
+func IsURLChanged(err error) bool {
+	var u *URLChangedError
+	return errors.As(err, &u)
+}
@@ -0,0 +1,158 @@
+package linkcheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/sqltime"
+	"github.com/jmoiron/sqlx"
+)
+
+// historyLength is how many checks are kept for each bookmark. Older ones
+// are deleted as new ones are recorded.
+const historyLength = 20
+
+// Target is a bookmarked page to check.
+type Target struct {
+	ID  int64
+	URL string
+}
+
+type Store interface {
+	Due(ctx context.Context, checkedBefore time.Time, limit int) ([]*Target, error)
+	Record(ctx context.Context, check *Check, brokenAfter int) (bookmark.LinkStatus, error)
+	List(ctx context.Context, bookmarkID int64) ([]*Check, error)
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{
+		db: sqlx.NewDb(db, "sqlite"),
+	}
+}
+
+type SQLiteStore struct {
+	db *sqlx.DB
+}
+
+// Due returns up to limit bookmarks, archived or not, that have never been
+// checked or were last checked before checkedBefore, those never checked
+// first and then the longest since their last check.
+func (s *SQLiteStore) Due(ctx context.Context, checkedBefore time.Time, limit int) ([]*Target, error) {
+	targets := []*Target{}
+	err := s.db.SelectContext(ctx, &targets, `
+        SELECT id, url
+        FROM bookmarks
+        WHERE link_checked_at IS NULL OR julianday(link_checked_at) < julianday(?)
+        ORDER BY link_checked_at IS NOT NULL, link_checked_at, id
+        LIMIT ?
+    `, sqltime.Julian(checkedBefore), limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmarks to check: %w", err)
+	}
+
+	return targets, nil
+}
+
+// Record stores a check and updates the link status of its bookmark to
+// match, returning the new status. See Summarize for how the status is
+// decided. A check of a URL the bookmark no longer has is a
+// *URLChangedError, and isn't stored.
+func (s *SQLiteStore) Record(ctx context.Context, check *Check, brokenAfter int) (bookmark.LinkStatus, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to record link check: %w", err)
+	}
+	defer tx.Rollback()
+
+	var b struct {
+		URL        string
+		LinkStatus bookmark.LinkStatus `db:"link_status"`
+	}
+	err = tx.GetContext(ctx, &b, "SELECT url, link_status FROM bookmarks WHERE id = ?", check.BookmarkID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", &bookmark.NotFoundError{Field: "id", Value: check.BookmarkID, Err: err}
+		}
+		return "", fmt.Errorf("failed to record link check: %w", err)
+	}
+	if b.URL != check.URL {
+		return "", &URLChangedError{BookmarkID: check.BookmarkID, URL: check.URL}
+	}
+
+	err = tx.GetContext(ctx, &check.ID, `
+        INSERT INTO link_checks (bookmark_id, checked_at, status, final_url, latency_ms, error)
+        VALUES (?, ?, ?, ?, ?, ?)
+        RETURNING id
+    `, check.BookmarkID, check.CheckedAt, check.Status, check.FinalURL, check.LatencyMS, check.Error)
+	if err != nil {
+		return "", fmt.Errorf("failed to record link check: %w", err)
+	}
+
+	recent := []*Check{}
+	err = tx.SelectContext(ctx, &recent, `
+        SELECT `+checkColumns+`
+        FROM link_checks
+        WHERE bookmark_id = ?
+        ORDER BY checked_at DESC, id DESC
+        LIMIT ?
+    `, check.BookmarkID, max(brokenAfter, 1))
+	if err != nil {
+		return "", fmt.Errorf("could not select link checks: %w", err)
+	}
+
+	status := Summarize(b.LinkStatus, check.Redirected, recent, brokenAfter)
+	_, err = tx.ExecContext(ctx, `
+        UPDATE bookmarks SET link_status = ?, link_checked_at = ? WHERE id = ?
+    `, status, check.CheckedAt, check.BookmarkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update link status: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        DELETE FROM link_checks
+        WHERE bookmark_id = ? AND id NOT IN (
+            SELECT id FROM link_checks WHERE bookmark_id = ? ORDER BY checked_at DESC, id DESC LIMIT ?
+        )
+    `, check.BookmarkID, check.BookmarkID, historyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to prune link checks: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", fmt.Errorf("failed to record link check: %w", err)
+	}
+
+	return status, nil
+}
+
+const checkColumns = "id, bookmark_id, checked_at, status, final_url, latency_ms, error"
+
+// List returns the checks kept for a bookmark, newest first.
+func (s *SQLiteStore) List(ctx context.Context, bookmarkID int64) ([]*Check, error) {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM bookmarks WHERE id = ?)", bookmarkID)
+	if err != nil {
+		return nil, fmt.Errorf("could not select bookmark: %w", err)
+	}
+	if !exists {
+		return nil, &bookmark.NotFoundError{Field: "id", Value: bookmarkID}
+	}
+
+	checks := []*Check{}
+	err = s.db.SelectContext(ctx, &checks, `
+        SELECT `+checkColumns+`
+        FROM link_checks
+        WHERE bookmark_id = ?
+        ORDER BY checked_at DESC, id DESC
+    `, bookmarkID)
+	if err != nil {
+		return nil, fmt.Errorf("could not select link checks: %w", err)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,36 @@
+// Package markdown renders the Markdown written in bookmark notes as HTML
+// that is safe to include in a page.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// policy allows the formatting people write in notes, but no scripts,
+	// styles, or frames. Links open in a new tab and don't pass on the page
+	// they were followed from.
+	policy = bluemonday.UGCPolicy().
+		RequireNoReferrerOnLinks(true).
+		AddTargetBlankToFullyQualifiedLinks(true)
+)
+
+// Render converts Markdown to sanitized HTML. Raw HTML in the source is left
+// out by the renderer and anything unsafe left in the output is stripped, so
+// the result can be used in a template as is.
+func Render(src string) (template.HTML, error) {
+	var buf bytes.Buffer
+	err := md.Convert([]byte(src), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil
+}
@@ -0,0 +1,80 @@
+// Package media finds bookmarks' favicons and preview images, and keeps
+// resized copies of them in a cache on disk.
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Cache stores images by the hash of their contents, so the same image used
+// by many bookmarks is only stored once and a stored image never changes.
+// Names are the hash followed by the image's extension, and files are
+// spread across subdirectories by the first two characters of the hash.
+type Cache struct {
+	dir string
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+var namePattern = regexp.MustCompile(`^[0-9a-f]{64}\.(png|jpg)$`)
+
+// ValidName reports whether name could be the name of a cached image, so
+// that names from requests can't reach outside the cache.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Path returns where the image called name is stored.
+func (c *Cache) Path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// Put stores data and returns its name. Storing an image that is already
+// cached does nothing.
+func (c *Cache) Put(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + "." + ext
+	path := c.Path(name)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return name, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return "", fmt.Errorf("could not create media directory: %w", err)
+	}
+
+	// write to a temporary file first, so that a cached file is never
+	// seen half-written
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("could not cache image: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not cache image: %w", err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return "", fmt.Errorf("could not cache image: %w", err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,145 @@
+package media
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmessinides/mnemonic/internal/metadata"
+)
+
+// candidate is an image that might be a page's icon.
+type candidate struct {
+	url string
+	// size is the larger side the page says the image has, or 0 if it
+	// doesn't say.
+	size int
+}
+
+// iconCandidates lists the images that might be a page's icon, best first:
+// the icons linked from the page or its manifest, largest first, and then
+// /favicon.ico, which browsers look for when a page doesn't link an icon.
+// meta may be nil if the page couldn't be fetched.
+func (w *Worker) iconCandidates(ctx context.Context, pageURL string, meta *metadata.Metadata) []string {
+	var found []candidate
+	if meta != nil {
+		for _, icon := range meta.Icons {
+			if isSVG(icon.URL, icon.Type) {
+				continue
+			}
+			found = append(found, candidate{url: icon.URL, size: largestSize(icon.Sizes)})
+		}
+
+		if meta.Manifest != "" {
+			found = append(found, w.manifestIcons(ctx, meta.Manifest)...)
+		}
+
+		pageURL = meta.URL
+	}
+
+	// a stable sort keeps the page's own order for icons of the same size
+	slices.SortStableFunc(found, func(a, b candidate) int {
+		return cmp.Compare(b.size, a.size)
+	})
+
+	urls := make([]string, 0, len(found)+1)
+	for _, c := range found {
+		if !slices.Contains(urls, c.url) {
+			urls = append(urls, c.url)
+		}
+	}
+
+	u, err := url.Parse(pageURL)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		favicon := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String()
+		if !slices.Contains(urls, favicon) {
+			urls = append(urls, favicon)
+		}
+	}
+
+	return urls
+}
+
+// manifestIcons reads the icons from a web app manifest. A manifest that
+// can't be fetched or read just has no icons.
+func (w *Worker) manifestIcons(ctx context.Context, manifestURL string) []candidate {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil
+	}
+
+	data, err := w.download(ctx, manifestURL)
+	if err != nil {
+		return nil
+	}
+
+	var manifest struct {
+		Icons []struct {
+			Src     string `json:"src"`
+			Sizes   string `json:"sizes"`
+			Type    string `json:"type"`
+			Purpose string `json:"purpose"`
+		} `json:"icons"`
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil
+	}
+
+	var found []candidate
+	for _, icon := range manifest.Icons {
+		// monochrome icons are single-colour silhouettes meant to be
+		// tinted, which look wrong as they are
+		if icon.Purpose != "" && !hasWord(icon.Purpose, "any") && !hasWord(icon.Purpose, "maskable") {
+			continue
+		}
+
+		u, err := base.Parse(icon.Src)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || isSVG(u.String(), icon.Type) {
+			continue
+		}
+
+		found = append(found, candidate{url: u.String(), size: largestSize(icon.Sizes)})
+	}
+
+	return found
+}
+
+// largestSize reads the largest side from a sizes attribute like
+// "16x16 32x32". "any" and anything unreadable count as 0.
+func largestSize(sizes string) int {
+	largest := 0
+	for _, s := range strings.Fields(strings.ToLower(sizes)) {
+		w, h, ok := strings.Cut(s, "x")
+		if !ok {
+			continue
+		}
+
+		wn, err1 := strconv.Atoi(w)
+		hn, err2 := strconv.Atoi(h)
+		if err1 == nil && err2 == nil {
+			largest = max(largest, wn, hn)
+		}
+	}
+
+	return largest
+}
+
+// isSVG reports whether an image is an SVG, which can't be decoded.
+func isSVG(u string, mediaType string) bool {
+	if strings.EqualFold(mediaType, "image/svg+xml") {
+		return true
+	}
+
+	parsed, err := url.Parse(u)
+	return err == nil && strings.EqualFold(path.Ext(parsed.Path), ".svg")
+}
+
+func hasWord(s string, word string) bool {
+	return slices.Contains(strings.Fields(s), word)
+}
@@ -0,0 +1,198 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// registered for image.Decode
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+)
+
+// maxPixels limits the size of an image that is decoded, since a small
+// file can describe an image far too large to hold in memory.
+const maxPixels = 40_000_000
+
+// decode reads an image in any of the formats sites use for icons and
+// previews: PNG, JPEG, GIF, WebP, BMP, and ICO. SVG isn't supported, since
+// it would have to be rendered rather than decoded.
+func decode(data []byte) (image.Image, error) {
+	if isICO(data) {
+		return decodeICO(data)
+	}
+
+	decodeImage := image.Decode
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		// BMPs aren't registered with the image package
+		config, err = bmp.DecodeConfig(bytes.NewReader(data))
+		decodeImage = func(r io.Reader) (image.Image, string, error) {
+			img, err := bmp.Decode(r)
+			return img, "bmp", err
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("could not decode image: %dx%d is too large", config.Width, config.Height)
+	}
+
+	img, _, err := decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+func isICO(data []byte) bool {
+	// reserved 0, type 1 for icons, and at least one image
+	return len(data) >= 6 &&
+		binary.LittleEndian.Uint16(data[0:]) == 0 &&
+		binary.LittleEndian.Uint16(data[2:]) == 1 &&
+		binary.LittleEndian.Uint16(data[4:]) > 0
+}
+
+// decodeICO decodes the largest image in an ICO file. Each image is either
+// a PNG or a BMP without its file header, whose height counts both the
+// image and the transparency mask after it.
+func decodeICO(data []byte) (image.Image, error) {
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if len(data) < 6+16*count {
+		return nil, errors.New("could not decode image: truncated icon")
+	}
+
+	var best []byte
+	bestSize := -1
+	for i := range count {
+		entry := data[6+16*i:]
+		// a width or height of 0 means 256
+		w, h := int(entry[0]), int(entry[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+
+		size := int(binary.LittleEndian.Uint32(entry[8:]))
+		offset := int(binary.LittleEndian.Uint32(entry[12:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			continue
+		}
+
+		if w*h > bestSize {
+			best = data[offset : offset+size]
+			bestSize = w * h
+		}
+	}
+	if best == nil {
+		return nil, errors.New("could not decode image: icon has no valid images")
+	}
+
+	if bytes.HasPrefix(best, []byte("\x89PNG")) {
+		return decode(best)
+	}
+
+	return decodeDIB(best)
+}
+
+// decodeDIB decodes a BMP stored in an icon by giving it the file header
+// it's missing and halving its height to leave out the mask.
+func decodeDIB(dib []byte) (image.Image, error) {
+	if len(dib) < 40 {
+		return nil, errors.New("could not decode image: truncated bitmap")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(dib[0:])
+	bpp := binary.LittleEndian.Uint16(dib[14:])
+	colors := binary.LittleEndian.Uint32(dib[32:])
+	if colors == 0 && bpp <= 8 {
+		colors = 1 << bpp
+	}
+
+	fixed := bytes.Clone(dib)
+	height := int32(binary.LittleEndian.Uint32(fixed[8:]))
+	binary.LittleEndian.PutUint32(fixed[8:], uint32(height/2))
+
+	file := make([]byte, 14, 14+len(fixed))
+	copy(file, "BM")
+	binary.LittleEndian.PutUint32(file[2:], uint32(14+len(fixed)))
+	binary.LittleEndian.PutUint32(file[10:], 14+headerSize+4*colors)
+	file = append(file, fixed...)
+
+	config, err := bmp.DecodeConfig(bytes.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("could not decode image: %dx%d is too large", config.Width, config.Height)
+	}
+
+	img, err := bmp.Decode(bytes.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+// fit scales img down to fit within a box of maxWidth by maxHeight, keeping
+// its proportions. Images that already fit are returned as they are.
+func fit(img image.Image, maxWidth int, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxWidth && h <= maxHeight {
+		return img
+	}
+
+	if w*maxHeight > h*maxWidth {
+		h = max(1, h*maxWidth/w)
+		w = maxWidth
+	} else {
+		w = max(1, w*maxHeight/h)
+		h = maxHeight
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// encodePNG is used for icons, which are small and often transparent.
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeJPEG is used for previews, which are usually photos. JPEG has no
+// transparency, so transparent areas are made white rather than black.
+func encodeJPEG(img image.Image) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,189 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/metadata"
+)
+
+type Options struct {
+	// IconSize is the largest an icon is stored, in pixels on each side.
+	IconSize int
+	// PreviewWidth and PreviewHeight are the largest a preview image is
+	// stored, in pixels.
+	PreviewWidth  int
+	PreviewHeight int
+	// MaxBytes limits the size of an image that is downloaded.
+	MaxBytes int64
+	// Timeout limits how long downloading one image can take.
+	Timeout time.Duration
+	// UserAgent is sent with the requests for icons, preview images, and
+	// web app manifests.
+	UserAgent string
+}
+
+// batchSize is how many bookmarks without media are loaded per round.
+const batchSize = 50
+
+// Worker finds the favicons and preview images of bookmarks that haven't
+// been checked yet, and stores them in the cache.
+type Worker struct {
+	store   bookmark.BookmarkStore
+	fetcher *metadata.Fetcher
+	cache   *Cache
+	client  *http.Client
+	opts    Options
+}
+
+// NewWorker returns a worker that downloads images with client, or
+// http.DefaultClient if client is nil. Pages whose metadata hasn't been
+// stored are fetched with fetcher to find their images.
+func NewWorker(store bookmark.BookmarkStore, fetcher *metadata.Fetcher, cache *Cache, client *http.Client, opts Options) *Worker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Worker{
+		store:   store,
+		fetcher: fetcher,
+		cache:   cache,
+		client:  client,
+		opts:    opts,
+	}
+}
+
+// Run checks every bookmark waiting to be checked, and returns how many it
+// checked. A bookmark whose images can't be found or downloaded is still
+// marked as checked, so it isn't tried again until its URL or metadata
+// changes.
+func (w *Worker) Run(ctx context.Context) (int, error) {
+	checked := 0
+	for {
+		pending, err := w.store.PendingMedia(ctx, batchSize)
+		if err != nil {
+			return checked, err
+		}
+		if len(pending) == 0 {
+			return checked, nil
+		}
+
+		for _, b := range pending {
+			err = w.store.SetMedia(ctx, b.ID, w.find(ctx, b))
+			if err != nil && !bookmark.IsNotFound(err) {
+				return checked, err
+			}
+			checked++
+		}
+	}
+}
+
+// find looks for a bookmark's icon and preview image and caches them.
+func (w *Worker) find(ctx context.Context, b *bookmark.Bookmark) bookmark.Media {
+	meta := b.Metadata
+	if meta == nil && w.fetcher != nil {
+		var err error
+		meta, err = w.fetcher.Fetch(ctx, b.URL)
+		if err != nil {
+			meta = nil
+		}
+	}
+
+	media := bookmark.Media{}
+	for _, u := range w.iconCandidates(ctx, b.URL, meta) {
+		name, err := w.cacheImage(ctx, u, w.opts.IconSize, w.opts.IconSize, encodePNG, "png")
+		if err == nil {
+			media.Icon = name
+			break
+		}
+	}
+
+	if meta != nil && meta.Image != "" {
+		name, err := w.cacheImage(ctx, meta.Image, w.opts.PreviewWidth, w.opts.PreviewHeight, encodeJPEG, "jpg")
+		if err == nil {
+			media.Preview = name
+		}
+	}
+
+	return media
+}
+
+// cacheImage downloads the image at u, resizes it to fit within width by
+// height, and caches it, returning its name in the cache.
+func (w *Worker) cacheImage(
+	ctx context.Context,
+	u string,
+	width int,
+	height int,
+	encode func(img image.Image) ([]byte, error),
+	ext string,
+) (string, error) {
+	data, err := w.download(ctx, u)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", u, err)
+	}
+
+	data, err = encode(fit(img, width, height))
+	if err != nil {
+		return "", fmt.Errorf("could not resize %s: %w", u, err)
+	}
+
+	return w.cache.Put(data, ext)
+}
+
+var errTooLarge = errors.New("response is too large")
+
+// download fetches u, failing if it doesn't succeed or is larger than the
+// size limit.
+func (w *Worker) download(ctx context.Context, u string) ([]byte, error) {
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", w.opts.UserAgent)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("could not download %s: the server responded with status %d", u, res.StatusCode)
+	}
+
+	var body io.Reader = res.Body
+	if w.opts.MaxBytes > 0 {
+		// one byte over the limit is enough to know it's too large
+		body = io.LimitReader(body, w.opts.MaxBytes+1)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", u, err)
+	}
+	if w.opts.MaxBytes > 0 && int64(len(data)) > w.opts.MaxBytes {
+		return nil, fmt.Errorf("could not download %s: %w", u, errTooLarge)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FetchError is returned when a page couldn't be fetched, or what was
+// fetched wasn't an HTML page.
+type FetchError struct {
+	URL string
+	// Status is the HTTP status of the response, or 0 if there wasn't one.
+	Status int
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("could not fetch %s: the server responded with status %d", e.URL, e.Status)
+	}
+
+	return fmt.Sprintf("could not fetch %s: %s", e.URL, e.Err.Error())
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+func IsFetchError(err error) bool {
+	var f *FetchError
+	return errors.As(err, &f)
+}
+
+// URLError is returned for a URL that can't be fetched at all, because it
+// can't be parsed or isn't http or https.
+type URLError struct {
+	URL string
+	Err error
+}
+
+func (e *URLError) Error() string {
+	return fmt.Sprintf("can't fetch %s: %s", e.URL, e.Err.Error())
+}
+
+func (e *URLError) Unwrap() error {
+	return e.Err
+}
+
+func IsURLError(err error) bool {
+	var u *URLError
+	return errors.As(err, &u)
+}
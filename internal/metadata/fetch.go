@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+type Options struct {
+	// Timeout limits how long fetching a page can take in total, including
+	// redirects and reading the body.
+	Timeout time.Duration
+	// MaxBytes limits how much of a page is read. Metadata is at the start
+	// of a page, so a page cut short usually still has all of it.
+	MaxBytes int64
+	// UserAgent identifies the fetcher to the sites it reads pages from.
+	// The client's default is sent when it is empty.
+	UserAgent string
+}
+
+// Fetcher fetches pages and reads their metadata.
+type Fetcher struct {
+	client *http.Client
+	opts   Options
+}
+
+// NewFetcher returns a fetcher that makes requests with client, or
+// http.DefaultClient if client is nil.
+func NewFetcher(client *http.Client, opts Options) *Fetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Fetcher{
+		client: client,
+		opts:   opts,
+	}
+}
+
+// Fetch fetches the page at rawURL and reads its metadata. Only http and
+// https URLs are fetched, and others are a *URLError. The response must be
+// HTML, or the page is a *FetchError like any other that can't be fetched.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &URLError{URL: rawURL, Err: err}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, &URLError{URL: rawURL, Err: fmt.Errorf("unsupported scheme %q", u.Scheme)}
+	}
+
+	if f.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, &URLError{URL: rawURL, Err: err}
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	if f.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", f.opts.UserAgent)
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, &FetchError{URL: rawURL, Err: err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &FetchError{URL: rawURL, Status: res.StatusCode}
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); contentType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, &FetchError{URL: rawURL, Err: fmt.Errorf("not an HTML page (%s)", mediaType)}
+	}
+
+	var body io.Reader = res.Body
+	if f.opts.MaxBytes > 0 {
+		body = io.LimitReader(body, f.opts.MaxBytes)
+	}
+
+	// pages that aren't UTF-8 are converted according to their
+	// Content-Type or <meta charset>
+	body, err = charset.NewReader(body, contentType)
+	if err != nil {
+		return nil, &FetchError{URL: rawURL, Err: err}
+	}
+
+	m, err := Parse(body, res.Request.URL)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, &FetchError{URL: rawURL, Err: err}
+		}
+		return nil, err
+	}
+	m.FetchedAt = time.Now()
+
+	return m, nil
+}
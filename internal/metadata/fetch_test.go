@@ -0,0 +1,280 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer serves each handler in pages at its path until the test
+// ends.
+func newTestServer(t *testing.T, pages map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	for path, h := range pages {
+		mux.HandleFunc(path, h)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// htmlPage serves body as a UTF-8 HTML page.
+func htmlPage(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(body))
+	}
+}
+
+func TestFetchTitle(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"/title": htmlPage(`<html lang="en"><head>
+			<title>
+				The   real
+				title
+			</title>
+			<meta property="og:title" content="OpenGraph title">
+			<meta name="twitter:title" content="Twitter title">
+			<meta name="description" content="The description">
+			<meta property="og:description" content="OpenGraph description">
+		</head><body><title>Not this</title></body></html>`),
+		"/og": htmlPage(`<head>
+			<meta property="og:title" content="OpenGraph title">
+			<meta name="twitter:title" content="Twitter title">
+			<meta property="og:description" content="OpenGraph description">
+			<meta property="og:site_name" content="Example">
+		</head>`),
+		"/twitter": htmlPage(`<head>
+			<meta name="twitter:title" content="Twitter title">
+			<meta property="twitter:description" content="Twitter description">
+		</head>`),
+	})
+
+	tests := []struct {
+		path        string
+		title       string
+		description string
+		siteName    string
+		language    string
+	}{
+		{"/title", "The real title", "The description", "", "en"},
+		{"/og", "OpenGraph title", "OpenGraph description", "Example", ""},
+		{"/twitter", "Twitter title", "Twitter description", "", ""},
+	}
+
+	f := NewFetcher(srv.Client(), Options{})
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			m, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.Title != tt.title {
+				t.Errorf("Title = %q, want %q", m.Title, tt.title)
+			}
+			if m.Description != tt.description {
+				t.Errorf("Description = %q, want %q", m.Description, tt.description)
+			}
+			if m.SiteName != tt.siteName {
+				t.Errorf("SiteName = %q, want %q", m.SiteName, tt.siteName)
+			}
+			if m.Language != tt.language {
+				t.Errorf("Language = %q, want %q", m.Language, tt.language)
+			}
+			if m.FetchedAt.IsZero() {
+				t.Error("FetchedAt is not set")
+			}
+		})
+	}
+}
+
+func TestFetchResolvesURLs(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"/old": func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/docs/page", http.StatusMovedPermanently)
+		},
+		"/docs/page": htmlPage(`<head>
+			<link rel="canonical" href="/docs/canonical">
+			<link rel="shortcut icon" href="favicon.ico">
+			<link rel="apple-touch-icon" href="../touch.png" sizes="180x180">
+			<link rel="icon" href="javascript:alert(1)">
+			<link rel="manifest" href="app.webmanifest">
+			<meta property="og:image" content="images/preview.png">
+		</head>`),
+		"/og-url": htmlPage(`<head>
+			<meta property="og:url" content="https://example.com/og">
+		</head>`),
+	})
+
+	f := NewFetcher(srv.Client(), Options{})
+	m, err := f.Fetch(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"URL":          srv.URL + "/docs/page",
+		"CanonicalURL": srv.URL + "/docs/canonical",
+		"Manifest":     srv.URL + "/docs/app.webmanifest",
+		"Image":        srv.URL + "/docs/images/preview.png",
+	}
+	got := map[string]string{
+		"URL":          m.URL,
+		"CanonicalURL": m.CanonicalURL,
+		"Manifest":     m.Manifest,
+		"Image":        m.Image,
+	}
+	for field, w := range want {
+		if got[field] != w {
+			t.Errorf("%s = %q, want %q", field, got[field], w)
+		}
+	}
+
+	icons := []Icon{
+		{URL: srv.URL + "/docs/favicon.ico"},
+		{URL: srv.URL + "/touch.png", Sizes: "180x180"},
+	}
+	if len(m.Icons) != len(icons) {
+		t.Fatalf("Icons = %v, want %v", m.Icons, icons)
+	}
+	for i := range icons {
+		if m.Icons[i] != icons[i] {
+			t.Errorf("Icons[%d] = %v, want %v", i, m.Icons[i], icons[i])
+		}
+	}
+
+	m, err = f.Fetch(context.Background(), srv.URL+"/og-url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CanonicalURL != "https://example.com/og" {
+		t.Errorf("CanonicalURL = %q, want the og:url", m.CanonicalURL)
+	}
+}
+
+func TestFetchCharset(t *testing.T) {
+	// "Café" in ISO-8859-1
+	latin1 := "<head><title>Caf\xe9</title></head>"
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"/header": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte(latin1))
+		},
+		"/meta": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<meta charset="windows-1252">` + latin1))
+		},
+	})
+
+	f := NewFetcher(srv.Client(), Options{})
+	for _, path := range []string{"/header", "/meta"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := f.Fetch(context.Background(), srv.URL+path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Title != "Café" {
+				t.Errorf("Title = %q, want %q", m.Title, "Café")
+			}
+		})
+	}
+}
+
+func TestFetchMaxBytes(t *testing.T) {
+	page := "<head><title>Early</title>" +
+		"<!--" + strings.Repeat("x", 4096) + "-->" +
+		`<meta name="description" content="Late">` +
+		"</head>"
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"/": htmlPage(page),
+	})
+
+	m, err := NewFetcher(srv.Client(), Options{MaxBytes: 1024}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Title != "Early" {
+		t.Errorf("Title = %q, want %q", m.Title, "Early")
+	}
+	if m.Description != "" {
+		t.Errorf("Description = %q, want it cut off", m.Description)
+	}
+
+	m, err = NewFetcher(srv.Client(), Options{}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Description != "Late" {
+		t.Errorf("Description = %q without MaxBytes, want %q", m.Description, "Late")
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"/json": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"title": "not a page"}`))
+		},
+		"/missing": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "<title>Not found</title>", http.StatusNotFound)
+		},
+		"/error": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	})
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{srv.URL + "/json", 0},
+		{srv.URL + "/missing", http.StatusNotFound},
+		{srv.URL + "/error", http.StatusInternalServerError},
+	}
+
+	f := NewFetcher(srv.Client(), Options{})
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			m, err := f.Fetch(context.Background(), tt.url)
+			if err == nil {
+				t.Fatalf("got %+v, want an error", m)
+			}
+
+			var fe *FetchError
+			if !errors.As(err, &fe) {
+				t.Fatalf("got %T (%v), want a *FetchError", err, err)
+			}
+			if fe.Status != tt.status {
+				t.Errorf("Status = %d, want %d", fe.Status, tt.status)
+			}
+			if fe.URL != tt.url {
+				t.Errorf("URL = %q, want %q", fe.URL, tt.url)
+			}
+		})
+	}
+}
+
+func TestFetchInvalidURL(t *testing.T) {
+	f := NewFetcher(nil, Options{})
+	for _, u := range []string{"ftp://example.com/file", "javascript:alert(1)", "http://[::1"} {
+		t.Run(u, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), u)
+
+			var ue *URLError
+			if !errors.As(err, &ue) {
+				t.Fatalf("got %T (%v), want a *URLError", err, err)
+			}
+			if IsFetchError(err) {
+				t.Errorf("got a *FetchError too (%v)", err)
+			}
+		})
+	}
+}
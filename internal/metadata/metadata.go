@@ -0,0 +1,76 @@
+// Package metadata fetches web pages and reads what they say about
+// themselves: their title, description, canonical URL, language, and
+// OpenGraph and Twitter card fields.
+package metadata
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Metadata describes a page. The main fields are the best of what the page
+// offers, so Title is the <title> element unless the page has none, in
+// which case it falls back to the OpenGraph or Twitter card title. Every
+// OpenGraph and Twitter card field is also kept as is, by property name
+// without its "og:" or "twitter:" prefix.
+type Metadata struct {
+	// URL is the address the page was fetched from, after redirects.
+	URL          string `json:"url"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CanonicalURL string `json:"canonicalUrl,omitempty"`
+	Language     string `json:"language,omitempty"`
+	SiteName     string `json:"siteName,omitempty"`
+	Image        string `json:"image,omitempty"`
+	// Icons are the page's icons from <link rel="icon"> and its variants,
+	// with absolute URLs.
+	Icons []Icon `json:"icons,omitempty"`
+	// Manifest is the absolute URL of the page's web app manifest.
+	Manifest  string            `json:"manifest,omitempty"`
+	OpenGraph map[string]string `json:"openGraph,omitempty"`
+	Twitter   map[string]string `json:"twitter,omitempty"`
+	FetchedAt time.Time         `json:"fetchedAt"`
+}
+
+// Icon is an icon linked from a page. Sizes is the sizes attribute as
+// written, like "32x32" or "16x16 32x32" or "any".
+type Icon struct {
+	URL   string `json:"url"`
+	Sizes string `json:"sizes,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+func (m *Metadata) Scan(src any) error {
+	var err error
+	switch s := src.(type) {
+	case []byte:
+		err = json.Unmarshal(s, m)
+	case string:
+		err = json.Unmarshal([]byte(s), m)
+	case nil:
+		return nil
+	default:
+		err = fmt.Errorf("cannot handle value of type %T", s)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to parse metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
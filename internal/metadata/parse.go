@@ -0,0 +1,170 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Parse reads the metadata in the <head> of an HTML page. Relative URLs in
+// it are resolved against base, which is where the page was fetched from.
+// Reading stops at the end of the head, or at the start of the body when
+// the end of the head is left out.
+func Parse(r io.Reader, base *url.URL) (*Metadata, error) {
+	m := &Metadata{
+		URL:       base.String(),
+		OpenGraph: map[string]string{},
+		Twitter:   map[string]string{},
+	}
+
+	var title *strings.Builder
+	var description, canonical, manifest string
+	z := html.NewTokenizer(r)
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if !errors.Is(z.Err(), io.EOF) {
+				return nil, fmt.Errorf("could not read page: %w", z.Err())
+			}
+
+			m.finish(description, canonical, manifest, base)
+			return m, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "html":
+				m.Language = strings.TrimSpace(attr(t, "lang"))
+			case "title":
+				if m.Title == "" {
+					title = &strings.Builder{}
+				}
+			case "meta":
+				name := strings.ToLower(attr(t, "name"))
+				property := strings.ToLower(attr(t, "property"))
+				content := strings.TrimSpace(attr(t, "content"))
+
+				switch {
+				case name == "description":
+					description = content
+				case strings.EqualFold(attr(t, "http-equiv"), "content-language") && m.Language == "":
+					m.Language = content
+				case strings.HasPrefix(property, "og:"):
+					m.OpenGraph[strings.TrimPrefix(property, "og:")] = content
+				// Twitter's own docs use name, but property is common too
+				case strings.HasPrefix(name, "twitter:"):
+					m.Twitter[strings.TrimPrefix(name, "twitter:")] = content
+				case strings.HasPrefix(property, "twitter:"):
+					m.Twitter[strings.TrimPrefix(property, "twitter:")] = content
+				}
+			case "link":
+				rel := attr(t, "rel")
+				switch {
+				case hasToken(rel, "canonical"):
+					canonical = attr(t, "href")
+				case hasToken(rel, "manifest"):
+					manifest = attr(t, "href")
+				// "shortcut icon" is an old spelling of "icon"
+				case hasToken(rel, "icon"), hasToken(rel, "apple-touch-icon"), hasToken(rel, "apple-touch-icon-precomposed"):
+					if u := resolve(base, attr(t, "href")); u != "" {
+						m.Icons = append(m.Icons, Icon{
+							URL:   u,
+							Sizes: strings.TrimSpace(attr(t, "sizes")),
+							Type:  strings.TrimSpace(attr(t, "type")),
+						})
+					}
+				}
+			case "body":
+				m.finish(description, canonical, manifest, base)
+				return m, nil
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "title":
+				if title != nil {
+					m.Title = strings.Join(strings.Fields(title.String()), " ")
+				}
+				title = nil
+			case "head":
+				m.finish(description, canonical, manifest, base)
+				return m, nil
+			}
+		case html.TextToken:
+			if title != nil {
+				title.Write(z.Text())
+			}
+		}
+	}
+}
+
+// finish fills in the main fields from the OpenGraph and Twitter card
+// fields where the page didn't set them itself.
+func (m *Metadata) finish(description string, canonical string, manifest string, base *url.URL) {
+	m.Title = first(m.Title, m.OpenGraph["title"], m.Twitter["title"])
+	m.Description = first(description, m.OpenGraph["description"], m.Twitter["description"])
+	m.SiteName = m.OpenGraph["site_name"]
+	m.Image = resolve(base, first(m.OpenGraph["image"], m.OpenGraph["image:url"], m.Twitter["image"], m.Twitter["image:src"]))
+	m.CanonicalURL = resolve(base, first(canonical, m.OpenGraph["url"]))
+	m.Language = first(m.Language, m.OpenGraph["locale"])
+	m.Manifest = resolve(base, manifest)
+
+	if len(m.OpenGraph) == 0 {
+		m.OpenGraph = nil
+	}
+	if len(m.Twitter) == 0 {
+		m.Twitter = nil
+	}
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// hasToken reports whether the space-separated list s, like a rel
+// attribute, contains token.
+func hasToken(s string, token string) bool {
+	for _, f := range strings.Fields(s) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// resolve returns ref as an absolute URL, or "" if it isn't a valid http
+// or https URL.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	return u.String()
+}
@@ -0,0 +1,212 @@
+// Package migrations keeps the database schema up to date.
+//
+// Each migration is a pair of embedded SQL files named
+// NNNN_description.up.sql and NNNN_description.down.sql. The version of the
+// last applied migration is stored in the database's user_version pragma.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Current int
+	Latest  int
+	Pending []*Migration
+}
+
+type TooNewError struct {
+	Version int
+	Latest  int
+}
+
+func (e *TooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the latest version this build knows about (%d); upgrade mnemonic before using this database", e.Version, e.Latest)
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: embedded,
+	}
+}
+
+var embedded = mustLoad(files)
+
+var filenamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+func mustLoad(fsys fs.FS) []*Migration {
+	entries, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		panic(err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, path := range entries {
+		m := filenamePattern.FindStringSubmatch(path[len("sql/"):])
+		if m == nil {
+			panic(fmt.Sprintf("migrations: malformed filename %s", path))
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			panic(err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, mig)
+	}
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return a.Version - b.Version
+	})
+
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			panic(fmt.Sprintf("migrations: expected version %d, found %d", i+1, mig.Version))
+		}
+		if mig.Up == "" || mig.Down == "" {
+			panic(fmt.Sprintf("migrations: version %d is missing an up or down file", mig.Version))
+		}
+	}
+
+	return migrations
+}
+
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+func (m *Migrator) Version() (int, error) {
+	var v int
+	err := m.db.QueryRow("PRAGMA user_version").Scan(&v)
+	if err != nil {
+		return 0, fmt.Errorf("could not read schema version: %w", err)
+	}
+
+	return v, nil
+}
+
+func (m *Migrator) Status() (*Status, error) {
+	v, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Status{
+		Current: v,
+		Latest:  m.Latest(),
+		Pending: []*Migration{},
+	}
+
+	if v < s.Latest {
+		s.Pending = m.migrations[v:]
+	}
+
+	return s, nil
+}
+
+// Up applies every pending migration in order, returning the ones it
+// applied. It returns a *TooNewError if the database has been migrated by a
+// newer build.
+func (m *Migrator) Up() ([]*Migration, error) {
+	s, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Current > s.Latest {
+		return nil, &TooNewError{Version: s.Current, Latest: s.Latest}
+	}
+
+	for i, mig := range s.Pending {
+		err = m.apply(mig.Up, mig.Version)
+		if err != nil {
+			return s.Pending[:i], fmt.Errorf("failed to apply migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return s.Pending, nil
+}
+
+// Down reverts the most recently applied migration, returning it, or nil if
+// no migrations have been applied.
+func (m *Migrator) Down() (*Migration, error) {
+	s, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Current > s.Latest {
+		return nil, &TooNewError{Version: s.Current, Latest: s.Latest}
+	}
+
+	if s.Current == 0 {
+		return nil, nil
+	}
+
+	mig := m.migrations[s.Current-1]
+	err = m.apply(mig.Down, mig.Version-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revert migration %d (%s): %w", mig.Version, mig.Name, err)
+	}
+
+	return mig, nil
+}
+
+func (m *Migrator) apply(script string, version int) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(script)
+	if err != nil {
+		return err
+	}
+
+	// user_version is stored in the database header, so it is committed or
+	// rolled back along with the rest of the transaction
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
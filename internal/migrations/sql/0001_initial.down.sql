@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS bookmarks_fts_delete;
+DROP TRIGGER IF EXISTS bookmarks_fts_update;
+DROP TRIGGER IF EXISTS bookmarks_fts_insert;
+DROP TABLE IF EXISTS bookmarks_fts;
+DROP VIEW IF EXISTS all_bookmarks;
+DROP VIEW IF EXISTS active_bookmarks;
+DROP TABLE IF EXISTS bookmarks;
@@ -0,0 +1,49 @@
+-- Databases created before migrations were introduced already have this
+-- schema at user_version 0, so every statement here must be idempotent.
+
+CREATE TABLE IF NOT EXISTS bookmarks
+    (
+        id INTEGER PRIMARY KEY,
+        title TEXT NOT NULL,
+        url TEXT UNIQUE NOT NULL,
+        tags TEXT DEFAULT "[]",
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        archived_at DATETIME
+    );
+
+CREATE VIEW IF NOT EXISTS active_bookmarks
+    AS SELECT id, title, url, tags, created_at, updated_at, (archived_at IS NOT NULL) archived
+    FROM bookmarks
+    WHERE archived_at IS NULL;
+
+CREATE VIEW IF NOT EXISTS all_bookmarks
+    AS SELECT id, title, url, tags, created_at, updated_at, (archived_at IS NOT NULL) archived
+    FROM bookmarks;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS bookmarks_fts
+    USING fts5(title, url, tags, tokenize = 'unicode61 remove_diacritics 2');
+
+CREATE TRIGGER IF NOT EXISTS bookmarks_fts_insert AFTER INSERT ON bookmarks
+    BEGIN
+        INSERT INTO bookmarks_fts (rowid, title, url, tags)
+            VALUES (NEW.id, NEW.title, NEW.url, (SELECT group_concat(value, ' ') FROM json_each(CAST(NEW.tags AS TEXT))));
+    END;
+
+CREATE TRIGGER IF NOT EXISTS bookmarks_fts_update AFTER UPDATE OF title, url, tags ON bookmarks
+    BEGIN
+        UPDATE bookmarks_fts
+            SET title = NEW.title, url = NEW.url, tags = (SELECT group_concat(value, ' ') FROM json_each(CAST(NEW.tags AS TEXT)))
+            WHERE rowid = NEW.id;
+    END;
+
+CREATE TRIGGER IF NOT EXISTS bookmarks_fts_delete AFTER DELETE ON bookmarks
+    BEGIN
+        DELETE FROM bookmarks_fts WHERE rowid = OLD.id;
+    END;
+
+-- index any bookmarks saved before the search index existed
+INSERT INTO bookmarks_fts (rowid, title, url, tags)
+    SELECT id, title, url, (SELECT group_concat(value, ' ') FROM json_each(CAST(bookmarks.tags AS TEXT)))
+    FROM bookmarks
+    WHERE id NOT IN (SELECT rowid FROM bookmarks_fts);
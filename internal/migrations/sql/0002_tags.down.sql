@@ -0,0 +1,38 @@
+DROP TRIGGER tags_fts_update;
+DROP TRIGGER bookmark_tags_fts_delete;
+DROP TRIGGER bookmark_tags_fts_insert;
+DROP TRIGGER tags_delete_bookmark_tags;
+DROP TRIGGER bookmarks_delete_tags;
+DROP TRIGGER bookmarks_fts_update;
+DROP TRIGGER bookmarks_fts_insert;
+DROP VIEW active_bookmarks;
+
+ALTER TABLE bookmarks ADD COLUMN tags TEXT DEFAULT "[]";
+
+UPDATE bookmarks SET tags = (SELECT a.tags FROM all_bookmarks a WHERE a.id = bookmarks.id);
+
+DROP VIEW all_bookmarks;
+DROP TABLE bookmark_tags;
+DROP TABLE tags;
+
+CREATE VIEW active_bookmarks
+    AS SELECT id, title, url, tags, created_at, updated_at, (archived_at IS NOT NULL) archived
+    FROM bookmarks
+    WHERE archived_at IS NULL;
+
+CREATE VIEW all_bookmarks
+    AS SELECT id, title, url, tags, created_at, updated_at, (archived_at IS NOT NULL) archived
+    FROM bookmarks;
+
+CREATE TRIGGER bookmarks_fts_insert AFTER INSERT ON bookmarks
+    BEGIN
+        INSERT INTO bookmarks_fts (rowid, title, url, tags)
+            VALUES (NEW.id, NEW.title, NEW.url, (SELECT group_concat(value, ' ') FROM json_each(CAST(NEW.tags AS TEXT))));
+    END;
+
+CREATE TRIGGER bookmarks_fts_update AFTER UPDATE OF title, url, tags ON bookmarks
+    BEGIN
+        UPDATE bookmarks_fts
+            SET title = NEW.title, url = NEW.url, tags = (SELECT group_concat(value, ' ') FROM json_each(CAST(NEW.tags AS TEXT)))
+            WHERE rowid = NEW.id;
+    END;
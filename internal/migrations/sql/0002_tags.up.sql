@@ -0,0 +1,112 @@
+CREATE TABLE tags
+    (
+        id INTEGER PRIMARY KEY,
+        name TEXT UNIQUE NOT NULL
+    );
+
+CREATE TABLE bookmark_tags
+    (
+        bookmark_id INTEGER NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+        tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+        PRIMARY KEY (bookmark_id, tag_id)
+    ) WITHOUT ROWID;
+
+CREATE INDEX bookmark_tags_tag_id ON bookmark_tags (tag_id);
+
+INSERT OR IGNORE INTO tags (name)
+    SELECT DISTINCT j.value
+    FROM bookmarks, json_each(CAST(bookmarks.tags AS TEXT)) j;
+
+INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id)
+    SELECT bookmarks.id, tags.id
+    FROM bookmarks, json_each(CAST(bookmarks.tags AS TEXT)) j
+    JOIN tags ON tags.name = j.value;
+
+DROP TRIGGER bookmarks_fts_insert;
+DROP TRIGGER bookmarks_fts_update;
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+ALTER TABLE bookmarks DROP COLUMN tags;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
+
+CREATE TRIGGER bookmarks_fts_insert AFTER INSERT ON bookmarks
+    BEGIN
+        INSERT INTO bookmarks_fts (rowid, title, url, tags) VALUES (NEW.id, NEW.title, NEW.url, '');
+    END;
+
+CREATE TRIGGER bookmarks_fts_update AFTER UPDATE OF title, url ON bookmarks
+    BEGIN
+        UPDATE bookmarks_fts SET title = NEW.title, url = NEW.url WHERE rowid = NEW.id;
+    END;
+
+-- foreign keys aren't necessarily enforced, so clean up join rows by hand
+CREATE TRIGGER bookmarks_delete_tags AFTER DELETE ON bookmarks
+    BEGIN
+        DELETE FROM bookmark_tags WHERE bookmark_id = OLD.id;
+    END;
+
+CREATE TRIGGER tags_delete_bookmark_tags AFTER DELETE ON tags
+    BEGIN
+        DELETE FROM bookmark_tags WHERE tag_id = OLD.id;
+    END;
+
+-- keep the tags column of the search index in sync with the join table
+CREATE TRIGGER bookmark_tags_fts_insert AFTER INSERT ON bookmark_tags
+    BEGIN
+        UPDATE bookmarks_fts
+            SET tags = (
+                SELECT group_concat(t.name, ' ')
+                FROM bookmark_tags bt
+                JOIN tags t ON t.id = bt.tag_id
+                WHERE bt.bookmark_id = NEW.bookmark_id
+            )
+            WHERE rowid = NEW.bookmark_id;
+    END;
+
+CREATE TRIGGER bookmark_tags_fts_delete AFTER DELETE ON bookmark_tags
+    BEGIN
+        UPDATE bookmarks_fts
+            SET tags = coalesce((
+                SELECT group_concat(t.name, ' ')
+                FROM bookmark_tags bt
+                JOIN tags t ON t.id = bt.tag_id
+                WHERE bt.bookmark_id = OLD.bookmark_id
+            ), '')
+            WHERE rowid = OLD.bookmark_id;
+
+        -- drop tags that are no longer used by any bookmark
+        DELETE FROM tags
+            WHERE id = OLD.tag_id
+            AND NOT EXISTS (SELECT 1 FROM bookmark_tags WHERE tag_id = OLD.tag_id);
+    END;
+
+CREATE TRIGGER tags_fts_update AFTER UPDATE OF name ON tags
+    BEGIN
+        UPDATE bookmarks_fts
+            SET tags = (
+                SELECT group_concat(t.name, ' ')
+                FROM bookmark_tags bt
+                JOIN tags t ON t.id = bt.tag_id
+                WHERE bt.bookmark_id = bookmarks_fts.rowid
+            )
+            WHERE rowid IN (SELECT bookmark_id FROM bookmark_tags WHERE tag_id = NEW.id);
+    END;
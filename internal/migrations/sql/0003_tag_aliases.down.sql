@@ -0,0 +1,24 @@
+DROP TRIGGER bookmark_tags_fts_delete;
+
+CREATE TRIGGER bookmark_tags_fts_delete AFTER DELETE ON bookmark_tags
+    BEGIN
+        UPDATE bookmarks_fts
+            SET tags = coalesce((
+                SELECT group_concat(t.name, ' ')
+                FROM bookmark_tags bt
+                JOIN tags t ON t.id = bt.tag_id
+                WHERE bt.bookmark_id = OLD.bookmark_id
+            ), '')
+            WHERE rowid = OLD.bookmark_id;
+
+        -- drop tags that are no longer used by any bookmark
+        DELETE FROM tags
+            WHERE id = OLD.tag_id
+            AND NOT EXISTS (SELECT 1 FROM bookmark_tags WHERE tag_id = OLD.tag_id);
+    END;
+
+DROP TRIGGER tags_delete_aliases;
+DROP TABLE tag_aliases;
+
+-- tags that were only kept around for their aliases
+DELETE FROM tags WHERE NOT EXISTS (SELECT 1 FROM bookmark_tags bt WHERE bt.tag_id = tags.id);
@@ -0,0 +1,35 @@
+CREATE TABLE tag_aliases
+    (
+        alias TEXT PRIMARY KEY,
+        tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE
+    ) WITHOUT ROWID;
+
+CREATE INDEX tag_aliases_tag_id ON tag_aliases (tag_id);
+
+-- foreign keys aren't necessarily enforced, so clean up aliases by hand
+CREATE TRIGGER tags_delete_aliases AFTER DELETE ON tags
+    BEGIN
+        DELETE FROM tag_aliases WHERE tag_id = OLD.id;
+    END;
+
+-- keep tags with aliases around even when no bookmark uses them, so that
+-- the aliases keep resolving
+DROP TRIGGER bookmark_tags_fts_delete;
+
+CREATE TRIGGER bookmark_tags_fts_delete AFTER DELETE ON bookmark_tags
+    BEGIN
+        UPDATE bookmarks_fts
+            SET tags = coalesce((
+                SELECT group_concat(t.name, ' ')
+                FROM bookmark_tags bt
+                JOIN tags t ON t.id = bt.tag_id
+                WHERE bt.bookmark_id = OLD.bookmark_id
+            ), '')
+            WHERE rowid = OLD.bookmark_id;
+
+        -- drop tags that are no longer used by any bookmark
+        DELETE FROM tags
+            WHERE id = OLD.tag_id
+            AND NOT EXISTS (SELECT 1 FROM bookmark_tags WHERE tag_id = OLD.tag_id)
+            AND NOT EXISTS (SELECT 1 FROM tag_aliases WHERE tag_id = OLD.tag_id);
+    END;
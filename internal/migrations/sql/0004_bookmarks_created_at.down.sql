@@ -0,0 +1 @@
+DROP INDEX bookmarks_created_at;
@@ -0,0 +1,2 @@
+-- lets bookmarks be paged by creation time without sorting the whole table
+CREATE INDEX bookmarks_created_at ON bookmarks (created_at, id);
@@ -0,0 +1,6 @@
+DROP INDEX bookmarks_created_julianday;
+DROP INDEX bookmarks_url_host;
+DROP INDEX bookmarks_title;
+DROP INDEX bookmarks_updated_at;
+
+ALTER TABLE bookmarks DROP COLUMN url_host;
@@ -0,0 +1,38 @@
+-- indexes for the orders bookmarks can be listed in; creation order is
+-- covered by bookmarks_created_at
+CREATE INDEX bookmarks_updated_at ON bookmarks (updated_at, id);
+CREATE INDEX bookmarks_title ON bookmarks (title COLLATE NOCASE, id);
+
+-- url_host is the lower-cased host name of the URL, without a port, for
+-- sorting by domain and site: filters. The application fills it in when
+-- bookmarks are saved; existing ones are filled in here by cutting the
+-- host out of the URL a piece at a time.
+ALTER TABLE bookmarks ADD COLUMN url_host TEXT;
+
+UPDATE bookmarks SET url_host = CASE
+    WHEN instr(url, '://') > 1 AND substr(url, 1, instr(url, '://') - 1) NOT GLOB '*[^A-Za-z0-9+.-]*'
+        THEN substr(url, instr(url, '://') + 3)
+    -- URLs without an authority have no host
+    ELSE ''
+END;
+-- the path, query, and fragment
+UPDATE bookmarks SET url_host = substr(url_host, 1, instr(url_host, '/') - 1) WHERE instr(url_host, '/') > 0;
+UPDATE bookmarks SET url_host = substr(url_host, 1, instr(url_host, '?') - 1) WHERE instr(url_host, '?') > 0;
+UPDATE bookmarks SET url_host = substr(url_host, 1, instr(url_host, '#') - 1) WHERE instr(url_host, '#') > 0;
+-- user info, up to the last @
+UPDATE bookmarks SET url_host = substr(url_host, length(rtrim(url_host, replace(url_host, '@', ''))) + 1)
+WHERE instr(url_host, '@') > 0;
+-- the port, and the brackets around an IPv6 address
+UPDATE bookmarks SET url_host = lower(CASE
+    WHEN url_host LIKE '[%]' OR url_host LIKE '[%]:%' THEN substr(url_host, 2, instr(url_host, ']') - 2)
+    WHEN url_host LIKE '[%' THEN ''
+    WHEN instr(url_host, ':') > 0 THEN substr(url_host, 1, instr(url_host, ':') - 1)
+    ELSE url_host
+END);
+
+CREATE INDEX bookmarks_url_host ON bookmarks (url_host, id);
+
+-- created_at is stored with the offset of the time zone the bookmark was
+-- saved in, so it is compared and ordered by julianday(created_at) instead,
+-- which needs an index on exactly that expression
+CREATE INDEX bookmarks_created_julianday ON bookmarks (julianday(created_at), id);
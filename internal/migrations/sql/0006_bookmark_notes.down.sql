@@ -0,0 +1,54 @@
+DROP TRIGGER bookmarks_fts_insert;
+DROP TRIGGER bookmarks_fts_update;
+DROP TABLE bookmarks_fts;
+
+CREATE VIRTUAL TABLE bookmarks_fts
+    USING fts5(title, url, tags, tokenize = 'unicode61 remove_diacritics 2');
+
+INSERT INTO bookmarks_fts (rowid, title, url, tags)
+    SELECT
+        b.id,
+        b.title,
+        b.url,
+        coalesce((
+            SELECT group_concat(t.name, ' ')
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ), '')
+    FROM bookmarks b;
+
+CREATE TRIGGER bookmarks_fts_insert AFTER INSERT ON bookmarks
+    BEGIN
+        INSERT INTO bookmarks_fts (rowid, title, url, tags) VALUES (NEW.id, NEW.title, NEW.url, '');
+    END;
+
+CREATE TRIGGER bookmarks_fts_update AFTER UPDATE OF title, url ON bookmarks
+    BEGIN
+        UPDATE bookmarks_fts SET title = NEW.title, url = NEW.url WHERE rowid = NEW.id;
+    END;
+
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+ALTER TABLE bookmarks DROP COLUMN notes;
+ALTER TABLE bookmarks DROP COLUMN description;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
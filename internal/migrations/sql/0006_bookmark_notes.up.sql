@@ -0,0 +1,64 @@
+ALTER TABLE bookmarks ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        b.description,
+        b.notes,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
+
+-- FTS5 tables can't gain columns, so the search index is rebuilt with the
+-- new ones. The triggers on bookmark_tags and tags find it by name, so they
+-- keep working.
+DROP TRIGGER bookmarks_fts_insert;
+DROP TRIGGER bookmarks_fts_update;
+DROP TABLE bookmarks_fts;
+
+CREATE VIRTUAL TABLE bookmarks_fts
+    USING fts5(title, url, tags, description, notes, tokenize = 'unicode61 remove_diacritics 2');
+
+INSERT INTO bookmarks_fts (rowid, title, url, tags, description, notes)
+    SELECT
+        b.id,
+        b.title,
+        b.url,
+        coalesce((
+            SELECT group_concat(t.name, ' ')
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ), ''),
+        b.description,
+        b.notes
+    FROM bookmarks b;
+
+CREATE TRIGGER bookmarks_fts_insert AFTER INSERT ON bookmarks
+    BEGIN
+        INSERT INTO bookmarks_fts (rowid, title, url, tags, description, notes)
+            VALUES (NEW.id, NEW.title, NEW.url, '', NEW.description, NEW.notes);
+    END;
+
+CREATE TRIGGER bookmarks_fts_update AFTER UPDATE OF title, url, description, notes ON bookmarks
+    BEGIN
+        UPDATE bookmarks_fts
+            SET title = NEW.title, url = NEW.url, description = NEW.description, notes = NEW.notes
+            WHERE rowid = NEW.id;
+    END;
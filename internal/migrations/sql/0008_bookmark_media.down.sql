@@ -0,0 +1,30 @@
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+DROP INDEX bookmarks_media_pending;
+
+ALTER TABLE bookmarks DROP COLUMN media_checked_at;
+ALTER TABLE bookmarks DROP COLUMN preview;
+ALTER TABLE bookmarks DROP COLUMN icon;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        b.description,
+        b.notes,
+        b.metadata,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
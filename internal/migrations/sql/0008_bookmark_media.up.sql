@@ -0,0 +1,35 @@
+-- icon and preview name images in the media cache, or are empty if the
+-- bookmark has none. media_checked_at is when they were last looked for,
+-- and is NULL for bookmarks waiting to be checked.
+ALTER TABLE bookmarks ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN preview TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN media_checked_at DATETIME;
+
+CREATE INDEX bookmarks_media_pending ON bookmarks (id) WHERE media_checked_at IS NULL;
+
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        b.description,
+        b.notes,
+        b.metadata,
+        b.icon,
+        b.preview,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
@@ -0,0 +1,3 @@
+DROP INDEX bookmarks_url_key;
+
+ALTER TABLE bookmarks DROP COLUMN url_key;
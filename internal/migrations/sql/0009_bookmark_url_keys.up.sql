@@ -0,0 +1,7 @@
+-- url_key identifies the page a bookmark is of, so that URLs that differ
+-- only in ways that don't matter are found to be duplicates. It depends on
+-- the URL rules in the config, so it is worked out by the application and
+-- is NULL until then.
+ALTER TABLE bookmarks ADD COLUMN url_key TEXT;
+
+CREATE INDEX bookmarks_url_key ON bookmarks (url_key);
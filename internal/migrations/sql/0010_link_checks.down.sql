@@ -0,0 +1,35 @@
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+DROP INDEX bookmarks_link_status;
+DROP INDEX bookmarks_link_checked_at;
+
+ALTER TABLE bookmarks DROP COLUMN link_checked_at;
+ALTER TABLE bookmarks DROP COLUMN link_status;
+
+DROP TRIGGER bookmarks_delete_link_checks;
+DROP TABLE link_checks;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        b.description,
+        b.notes,
+        b.metadata,
+        b.icon,
+        b.preview,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
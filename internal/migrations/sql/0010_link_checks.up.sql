@@ -0,0 +1,56 @@
+-- link_checks keeps the history of requests made to see whether bookmarked
+-- pages still exist. status is NULL when no response was received, in which
+-- case error says why.
+CREATE TABLE link_checks
+    (
+        id INTEGER PRIMARY KEY,
+        bookmark_id INTEGER NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+        checked_at DATETIME NOT NULL,
+        status INTEGER,
+        final_url TEXT NOT NULL DEFAULT '',
+        latency_ms INTEGER NOT NULL,
+        error TEXT NOT NULL DEFAULT ''
+    );
+
+CREATE INDEX link_checks_bookmark_id ON link_checks (bookmark_id, checked_at);
+
+CREATE TRIGGER bookmarks_delete_link_checks AFTER DELETE ON bookmarks
+    BEGIN
+        DELETE FROM link_checks WHERE bookmark_id = OLD.id;
+    END;
+
+-- link_status sums up the latest checks: unchecked, ok, redirected, or
+-- broken.
+ALTER TABLE bookmarks ADD COLUMN link_status TEXT NOT NULL DEFAULT 'unchecked';
+ALTER TABLE bookmarks ADD COLUMN link_checked_at DATETIME;
+
+CREATE INDEX bookmarks_link_checked_at ON bookmarks (link_checked_at);
+CREATE INDEX bookmarks_link_status ON bookmarks (link_status);
+
+DROP VIEW active_bookmarks;
+DROP VIEW all_bookmarks;
+
+CREATE VIEW all_bookmarks
+    AS SELECT
+        b.id,
+        b.title,
+        b.url,
+        b.description,
+        b.notes,
+        b.metadata,
+        b.icon,
+        b.preview,
+        b.link_status,
+        (
+            SELECT json_group_array(t.name ORDER BY t.name)
+            FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            WHERE bt.bookmark_id = b.id
+        ) tags,
+        b.created_at,
+        b.updated_at,
+        (b.archived_at IS NOT NULL) archived
+    FROM bookmarks b;
+
+CREATE VIEW active_bookmarks
+    AS SELECT * FROM all_bookmarks WHERE NOT archived;
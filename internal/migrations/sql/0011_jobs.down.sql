@@ -0,0 +1,5 @@
+DROP TABLE job_schedules;
+
+DROP INDEX jobs_kind;
+DROP INDEX jobs_status_run_at;
+DROP TABLE jobs;
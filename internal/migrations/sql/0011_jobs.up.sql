@@ -0,0 +1,32 @@
+-- jobs is the queue of background work. A job is pending until a worker
+-- leases it, which makes it running until leased_until, so that a job left
+-- running by a crashed process is picked up again. Jobs that fail are
+-- retried until they have made max_attempts attempts, and then are dead
+-- until they are retried by hand.
+CREATE TABLE jobs
+    (
+        id INTEGER PRIMARY KEY,
+        kind TEXT NOT NULL,
+        payload TEXT NOT NULL DEFAULT 'null',
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INTEGER NOT NULL DEFAULT 0,
+        max_attempts INTEGER NOT NULL,
+        run_at DATETIME NOT NULL,
+        leased_until DATETIME,
+        last_error TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        finished_at DATETIME
+    );
+
+CREATE INDEX jobs_status_run_at ON jobs (status, run_at);
+CREATE INDEX jobs_kind ON jobs (kind, status);
+
+-- job_schedules remembers when each recurring job is next due, so that
+-- restarting the server doesn't run them all again or skip them.
+CREATE TABLE job_schedules
+    (
+        kind TEXT PRIMARY KEY,
+        spec TEXT NOT NULL,
+        next_run_at DATETIME NOT NULL
+    );
@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor marks a position in a list ordered by creation time and then ID.
+// Unlike a page number, a cursor keeps its place when items are added or
+// removed before it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+	// Before is true for a cursor to the items before the position rather
+	// than after it.
+	Before bool
+}
+
+// String encodes the cursor as an opaque, URL-safe token that ParseCursor
+// reads back. The creation time keeps its offset so that it compares
+// exactly with the stored value.
+func (c *Cursor) String() string {
+	dir := "a"
+	if c.Before {
+		dir = "b"
+	}
+
+	s := dir + "|" + c.CreatedAt.Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, &InvalidCursorError{Cursor: s, Err: err}
+	}
+
+	parts := strings.Split(string(b), "|")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "b") {
+		return nil, &InvalidCursorError{Cursor: s}
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, &InvalidCursorError{Cursor: s, Err: err}
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, &InvalidCursorError{Cursor: s, Err: err}
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id, Before: parts[0] == "b"}, nil
+}
+
+type CursorParams struct {
+	// Cursor is where the page starts, or nil for the first page.
+	Cursor   *Cursor
+	PageSize uint64
+	// Desc lists the newest items first.
+	Desc bool
+	// SkipTotal leaves out the total number of items, which costs a count
+	// of every matching row.
+	SkipTotal bool
+}
+
+// CursorPage is a page of items found with a cursor. Next and Prev are the
+// cursors to the pages after and before it, and are empty when there is no
+// such page.
+type CursorPage[T any] struct {
+	Items    []T     `json:"items"`
+	PageSize uint64  `json:"pageSize"`
+	Next     string  `json:"next,omitempty"`
+	Prev     string  `json:"prev,omitempty"`
+	Total    *uint64 `json:"total,omitempty"`
+}
@@ -0,0 +1,29 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+)
+
+type InvalidCursorError struct {
+	Cursor string
+	Err    error
+}
+
+func (e *InvalidCursorError) Error() string {
+	msg := fmt.Sprintf("invalid cursor %q", e.Cursor)
+	if e.Err != nil {
+		msg = msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *InvalidCursorError) Unwrap() error {
+	return e.Err
+}
+
+func IsInvalidCursor(err error) bool {
+	var c *InvalidCursorError
+	return errors.As(err, &c)
+}
@@ -5,4 +5,34 @@ type Page[T any] struct {
 	Page       uint64 `json:"page"`
 	PageSize   uint64 `json:"pageSize"`
 	TotalPages uint64 `json:"totalPages"`
+	TotalItems uint64 `json:"totalItems"`
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+}
+
+// NewPage returns page number page of a list of totalItems items, which
+// holds items. There is always at least one page, even when the list is
+// empty.
+func NewPage[T any](items []T, page uint64, pageSize uint64, totalItems uint64) *Page[T] {
+	totalPages := max((totalItems+pageSize-1)/pageSize, 1)
+
+	return &Page[T]{
+		Items:      items,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalItems: totalItems,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+// Offset returns the number of items before page number page, counting
+// from 1, for use in an OFFSET clause.
+func Offset(page uint64, pageSize uint64) uint64 {
+	if page == 0 {
+		return 0
+	}
+
+	return (page - 1) * pageSize
 }
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenTerm
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind   tokenKind
+	pos    int
+	text   string
+	field  string
+	value  string
+	quoted bool
+}
+
+type lexer struct {
+	input  string
+	pos    int
+	tokens []token
+}
+
+func lex(s string) []token {
+	l := &lexer{input: s}
+	l.run()
+	return l.tokens
+}
+
+func (l *lexer) emit(t token) {
+	l.tokens = append(l.tokens, t)
+}
+
+func (l *lexer) run() {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.input) {
+			l.emit(token{kind: tokenEOF, pos: l.pos})
+			return
+		}
+
+		start := l.pos
+		switch c := l.input[l.pos]; c {
+		case '(':
+			l.pos++
+			l.emit(token{kind: tokenLParen, pos: start, text: "("})
+		case ')':
+			l.pos++
+			l.emit(token{kind: tokenRParen, pos: start, text: ")"})
+		case '-':
+			l.pos++
+			if l.pos < len(l.input) && !isSpace(l.input[l.pos:]) {
+				l.emit(token{kind: tokenNot, pos: start, text: "-"})
+			}
+		case '"':
+			value := l.quoted()
+			l.emit(token{kind: tokenTerm, pos: start, text: l.input[start:l.pos], value: value, quoted: true})
+		default:
+			l.term()
+		}
+	}
+}
+
+func (l *lexer) term() {
+	start := l.pos
+	word := l.word()
+
+	if word == "OR" {
+		l.emit(token{kind: tokenOr, pos: start, text: word})
+		return
+	}
+
+	t := token{kind: tokenTerm, pos: start, value: word}
+	if name, value, ok := strings.Cut(word, ":"); ok && fields[strings.ToLower(name)] {
+		t.field = name
+		t.value = value
+		if value == "" && l.pos < len(l.input) && l.input[l.pos] == '"' {
+			t.value = l.quoted()
+			t.quoted = true
+		}
+	}
+
+	t.text = l.input[start:l.pos]
+	l.emit(t)
+}
+
+// word consumes input up to the next space, parenthesis, or quote.
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		if strings.ContainsRune(`()"`, rune(l.input[l.pos])) || isSpace(l.input[l.pos:]) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		l.pos += size
+	}
+
+	return l.input[start:l.pos]
+}
+
+// quoted consumes a double-quoted string, returning its contents. An
+// unterminated quote runs to the end of the input.
+func (l *lexer) quoted() string {
+	l.pos++
+	start := l.pos
+	end := strings.IndexByte(l.input[start:], '"')
+	if end < 0 {
+		l.pos = len(l.input)
+		return l.input[start:]
+	}
+
+	l.pos = start + end + 1
+	return l.input[start : start+end]
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.input) && isSpace(l.input[l.pos:]) {
+		_, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		l.pos += size
+	}
+}
+
+func isSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,250 @@
+// Package query parses the search syntax accepted by the bookmarks API and
+// the search page.
+//
+// A query is a list of terms that must all match. Terms may be plain words,
+// "quoted phrases", or field filters:
+//
+//	tag:go            tagged with "go" or a tag beneath it, like "go/generics",
+//	                  or with the tag "go" is an alias of
+//	site:github.com   saved from github.com or any of its subdomains
+//	is:archived       archived (or is:active for bookmarks that are not)
+//	before:2025-01-01 created before the start of the given day
+//	after:2025-01-01  created on or after the start of the given day
+//
+// Any term can be negated with a leading "-", terms can be grouped with
+// parentheses, and the OR keyword matches either of its neighbours:
+//
+//	tag:go (generics OR "type parameters") -is:archived
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Node interface {
+	node()
+}
+
+// And matches when every one of its nodes matches.
+type And []Node
+
+// Or matches when any one of its nodes matches.
+type Or []Node
+
+// Not matches when its node does not.
+type Not struct {
+	Node Node
+}
+
+// Text matches bookmarks containing a word, or an exact phrase when Phrase
+// is set.
+type Text struct {
+	Value  string
+	Phrase bool
+}
+
+// Field matches bookmarks by one of their attributes.
+type Field struct {
+	Name  string
+	Value string
+}
+
+func (And) node()   {}
+func (Or) node()    {}
+func (Not) node()   {}
+func (Text) node()  {}
+func (Field) node() {}
+
+const (
+	FieldTag    = "tag"
+	FieldSite   = "site"
+	FieldIs     = "is"
+	FieldBefore = "before"
+	FieldAfter  = "after"
+)
+
+var fields = map[string]bool{
+	FieldTag:    true,
+	FieldSite:   true,
+	FieldIs:     true,
+	FieldBefore: true,
+	FieldAfter:  true,
+}
+
+const dateLayout = "2006-01-02"
+
+type Query struct {
+	// Root is nil for a query with no terms.
+	Root Node
+	raw  string
+}
+
+func (q *Query) String() string {
+	return q.raw
+}
+
+// IsEmpty reports whether the query has no terms, in which case it matches
+// nothing.
+func (q *Query) IsEmpty() bool {
+	return q == nil || q.Root == nil
+}
+
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+func Parse(s string) (*Query, error) {
+	p := &parser{tokens: lex(s)}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+
+	return &Query{Root: root, raw: s}, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	var nodes Or
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+
+		if p.peek().kind != tokenOr {
+			break
+		}
+		p.next()
+	}
+
+	switch len(nodes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return nodes[0], nil
+	default:
+		return nodes, nil
+	}
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var nodes And
+	for {
+		switch p.peek().kind {
+		case tokenEOF, tokenOr, tokenRParen:
+			switch len(nodes) {
+			case 0:
+				return nil, nil
+			case 1:
+				return nodes[0], nil
+			default:
+				return nodes, nil
+			}
+		}
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNot:
+		n, err := p.parseUnary()
+		if err != nil || n == nil {
+			return nil, err
+		}
+
+		return Not{Node: n}, nil
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "unclosed parenthesis"}
+		}
+
+		return n, nil
+	case tokenRParen:
+		return nil, &SyntaxError{Pos: t.pos, Msg: `unexpected ")"`}
+	case tokenTerm:
+		return p.parseTerm(t)
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+}
+
+func (p *parser) parseTerm(t token) (Node, error) {
+	if t.field == "" {
+		if t.value == "" {
+			return nil, nil
+		}
+
+		return Text{Value: t.value, Phrase: t.quoted}, nil
+	}
+
+	name := strings.ToLower(t.field)
+	value := t.value
+	if value == "" {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("%s: requires a value", name)}
+	}
+
+	switch name {
+	case FieldIs:
+		value = strings.ToLower(value)
+		if value != "archived" && value != "active" {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unknown value for is: %q (expected archived or active)", t.value)}
+		}
+	case FieldBefore, FieldAfter:
+		if _, err := time.ParseInLocation(dateLayout, value, time.Local); err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("%s: expects a date like 2025-01-31", name)}
+		}
+	case FieldSite:
+		value = strings.ToLower(value)
+	}
+
+	return Field{Name: name, Value: value}, nil
+}
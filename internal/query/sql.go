@@ -0,0 +1,139 @@
+package query
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cmessinides/mnemonic/internal/sqltime"
+	"github.com/cmessinides/mnemonic/internal/tag"
+)
+
+// SQL is a query compiled for use against the bookmarks table, which must be
+// aliased as b, its tags, and its full-text index bookmarks_fts.
+type SQL struct {
+	// Match is an FTS5 expression of the words and phrases every result must
+	// contain. It is empty when the query has no required text, in which
+	// case results cannot be ranked by relevance.
+	Match string
+	// Where is a boolean SQL expression for the rest of the query. It is
+	// always safe to include in a WHERE clause.
+	Where string
+	Args  []any
+}
+
+func (q *Query) SQL() *SQL {
+	s := &SQL{}
+
+	var match []string
+	var rest And
+	switch n := q.Root.(type) {
+	case nil:
+	case Text:
+		match = append(match, ftsTerm(n))
+	case And:
+		for _, c := range n {
+			if t, ok := c.(Text); ok {
+				match = append(match, ftsTerm(t))
+			} else {
+				rest = append(rest, c)
+			}
+		}
+	default:
+		rest = append(rest, n)
+	}
+
+	s.Match = strings.Join(match, " ")
+	if len(rest) == 0 {
+		s.Where = "1"
+	} else {
+		s.Where = s.compile(rest)
+	}
+
+	return s
+}
+
+func (s *SQL) compile(n Node) string {
+	switch n := n.(type) {
+	case And:
+		return s.join(n, " AND ")
+	case Or:
+		return s.join(n, " OR ")
+	case Not:
+		return "NOT " + s.compile(n.Node)
+	case Text:
+		s.Args = append(s.Args, ftsTerm(n))
+		return "b.id IN (SELECT rowid FROM bookmarks_fts WHERE bookmarks_fts MATCH ?)"
+	case Field:
+		return s.compileField(n)
+	default:
+		panic("query: unknown node type")
+	}
+}
+
+func (s *SQL) join(nodes []Node, op string) string {
+	parts := make([]string, len(nodes))
+	for i, c := range nodes {
+		parts[i] = s.compile(c)
+	}
+
+	return "(" + strings.Join(parts, op) + ")"
+}
+
+func (s *SQL) compileField(f Field) string {
+	switch f.Name {
+	case FieldTag:
+		// match the tag, or the tag it is an alias of, and everything
+		// beneath it
+		s.Args = append(s.Args, f.Value, f.Value, f.Value, f.Value, tag.Separator, tag.Separator)
+		return `EXISTS (
+            SELECT 1 FROM bookmark_tags bt
+            JOIN tags t ON t.id = bt.tag_id
+            JOIN (
+                SELECT ? name
+                UNION
+                SELECT at.name || substr(?, length(a.alias) + 1)
+                FROM tag_aliases a
+                JOIN tags at ON at.id = a.tag_id
+                WHERE ? = a.alias COLLATE NOCASE
+                OR substr(?, 1, length(a.alias) + 1) = a.alias || ? COLLATE NOCASE
+            ) m ON t.name = m.name COLLATE NOCASE
+                OR substr(t.name, 1, length(m.name) + 1) = m.name || ? COLLATE NOCASE
+            WHERE bt.bookmark_id = b.id
+        )`
+	case FieldSite:
+		s.Args = append(s.Args, f.Value, "%."+escapeLike(f.Value))
+		return `(b.url_host = ? OR b.url_host LIKE ? ESCAPE '\')`
+	case FieldIs:
+		if f.Value == "archived" {
+			return "b.archived_at IS NOT NULL"
+		}
+		return "b.archived_at IS NULL"
+	case FieldBefore, FieldAfter:
+		// validated by the parser
+		t, _ := time.ParseInLocation(dateLayout, f.Value, time.Local)
+		s.Args = append(s.Args, sqltime.Julian(t))
+		if f.Name == FieldBefore {
+			return "julianday(b.created_at) < julianday(?)"
+		}
+		return "julianday(b.created_at) >= julianday(?)"
+	default:
+		panic("query: unknown field " + f.Name)
+	}
+}
+
+// ftsTerm quotes t for FTS5 so that operators in user input are treated as
+// plain text. Words match as prefixes; phrases match exactly.
+func ftsTerm(t Text) string {
+	quoted := `"` + strings.ReplaceAll(t.Value, `"`, `""`) + `"`
+	if t.Phrase {
+		return quoted
+	}
+
+	return quoted + "*"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/cmessinides/mnemonic/internal/backup"
+	"github.com/cmessinides/mnemonic/internal/jobs"
+	"github.com/labstack/echo/v4"
+)
+
+type adminAPI struct {
+	backups *backup.Manager
+	jobs    jobs.Store
+}
+
+// Backup makes a backup of the database right away, rather than waiting for
+// the next scheduled one.
+func (a *adminAPI) Backup(c echo.Context) error {
+	f, err := a.backups.Backup()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).WithInternal(err)
+	}
+
+	return c.JSON(http.StatusCreated, f)
+}
+
+// ListJobs returns a page of background jobs, newest first, filtered by the
+// status (pending, running, done, or dead) and kind query parameters.
+func (a *adminAPI) ListJobs(c echo.Context) error {
+	page, pageSize, err := bindPageParams(c)
+	if err != nil {
+		return err
+	}
+
+	filter := jobs.Filter{Kind: c.QueryParam("kind")}
+	if v := c.QueryParam("status"); v != "" {
+		filter.Status, err = jobs.ParseStatus(v)
+		if err != nil {
+			return newValidationError(&fieldError{Field: "status", Message: "must be pending, running, done, or dead"}).WithInternal(err)
+		}
+	}
+
+	jp, err := a.jobs.GetPage(c.Request().Context(), page, pageSize, filter)
+	if err != nil {
+		return failJob(err)
+	}
+
+	setPageLinks(c, jp.Page, jp.TotalPages)
+	return c.JSON(http.StatusOK, jp)
+}
+
+func (a *adminAPI) ReadJob(c echo.Context) error {
+	id, err := jobIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := a.jobs.Get(c.Request().Context(), id)
+	if err != nil {
+		return failJob(err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// RetryJob runs a job that failed for good again, with all of its attempts.
+func (a *adminAPI) RetryJob(c echo.Context) error {
+	id, err := jobIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	job, err := a.jobs.Retry(c.Request().Context(), id)
+	if err != nil {
+		return failJob(err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func jobIDParam(c echo.Context) (int64, error) {
+	var id int64
+
+	err := echo.PathParamsBinder(c).
+		MustInt64("id", &id).
+		BindError()
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
+	}
+
+	return id, nil
+}
+
+func failJob(err error) *echo.HTTPError {
+	if jobs.IsNotFound(err) {
+		return echo.NewHTTPError(http.StatusNotFound, "job not found").WithInternal(err)
+	}
+
+	if jobs.IsNotDead(err) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error()).WithInternal(err)
+	}
+
+	return echo.NewHTTPError(http.StatusInternalServerError).WithInternal(err)
+}
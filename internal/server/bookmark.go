@@ -0,0 +1,46 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/markdown"
+	"github.com/labstack/echo/v4"
+)
+
+type bookmarkController struct {
+	bookmarks bookmark.BookmarkStore
+}
+
+func (b *bookmarkController) Show(c echo.Context) error {
+	var data struct {
+		View     string
+		Bookmark *bookmark.Bookmark
+		Notes    template.HTML
+	}
+	data.View = "bookmark"
+
+	var id int64
+	err := echo.PathParamsBinder(c).
+		MustInt64("id", &id).
+		BindError()
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	data.Bookmark, err = b.bookmarks.Get(c.Request().Context(), id)
+	if err != nil {
+		if bookmark.IsNotFound(err) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+
+	data.Notes, err = markdown.Render(data.Bookmark.Notes)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, "bookmark.html", data)
+}
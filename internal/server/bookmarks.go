@@ -1,37 +1,88 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/jsonpatch"
+	"github.com/cmessinides/mnemonic/internal/metadata"
+	"github.com/cmessinides/mnemonic/internal/pagination"
+	"github.com/cmessinides/mnemonic/internal/query"
+	"github.com/cmessinides/mnemonic/internal/tag"
 	"github.com/labstack/echo/v4"
 )
 
 type bookmarksAPI struct {
-	store bookmark.BookmarkStore
+	store   bookmark.BookmarkStore
+	fetcher *metadata.Fetcher
 }
 
 func (a *bookmarksAPI) Create(c echo.Context) error {
 	var init struct {
-		Title string
-		URL   string
-		Tags  []string
+		Title       string   `json:"title"`
+		URL         string   `json:"url"`
+		Description string   `json:"description"`
+		Notes       string   `json:"notes"`
+		Tags        []string `json:"tags"`
 	}
 
-	init.Tags = []string{}
+	err := bindBody(c, &init, func(b *echo.ValueBinder) {
+		b.String("title", &init.Title).
+			String("url", &init.URL).
+			String("description", &init.Description).
+			String("notes", &init.Notes).
+			Strings("tags", &init.Tags)
+	})
+	if err != nil {
+		return err
+	}
 
-	err := echo.FormFieldBinder(c).
-		MustString("title", &init.Title).
-		MustString("url", &init.URL).
-		Strings("tags", &init.Tags).
-		BindError()
+	if init.Tags == nil {
+		init.Tags = []string{}
+	}
+
+	err = validate(
+		validateURL(&init.URL),
+		validateDescription(&init.Description),
+	)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+		return err
 	}
 
-	b, err := a.store.Create(init.Title, init.URL, init.Tags)
+	// without a title, the page is fetched to find one. A page that can't be
+	// fetched doesn't stop the bookmark being saved; it's titled with its
+	// URL instead, and can be refreshed later.
+	var meta *metadata.Metadata
+	if strings.TrimSpace(init.Title) == "" {
+		meta, err = a.fetcher.Fetch(c.Request().Context(), init.URL)
+		if err != nil {
+			c.Logger().Warn(err)
+		}
+
+		init.Title, init.Description = fillFromMetadata(init.URL, init.Title, init.Description, meta)
+	}
+
+	b, err := a.store.Create(c.Request().Context(), bookmark.BookmarkInit{
+		Title:       init.Title,
+		URL:         init.URL,
+		Description: init.Description,
+		Notes:       init.Notes,
+		Metadata:    meta,
+		Tags:        init.Tags,
+	})
 	if err != nil {
 		return fail(err)
 	}
@@ -49,7 +100,7 @@ func (a *bookmarksAPI) Read(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
 	}
 
-	b, err := a.store.Get(id)
+	b, err := a.store.Get(c.Request().Context(), id)
 	if err != nil {
 		return fail(err)
 	}
@@ -59,68 +110,397 @@ func (a *bookmarksAPI) Read(c echo.Context) error {
 
 func (a *bookmarksAPI) Update(c echo.Context) error {
 	var id int64
-	var title string
-	var url string
-	var archived bool
-	var tags []string
 
 	err := echo.PathParamsBinder(c).
 		MustInt64("id", &id).
 		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
+	}
+
+	var patch *bookmark.BookmarkPatch
+	switch mediaType(c) {
+	case echo.MIMEApplicationJSON, jsonpatch.MergePatchMediaType:
+		// a plain JSON body updates only the fields it contains, which is
+		// exactly what a merge patch does
+		patch, err = a.bindJSONPatch(c, id, jsonpatch.MergePatch)
+	case jsonpatch.JSONPatchMediaType:
+		patch, err = a.bindJSONPatch(c, id, jsonpatch.Apply)
+	case echo.MIMEApplicationForm, echo.MIMEMultipartForm:
+		patch, err = bindFormPatch(c, id)
+	default:
+		return echo.ErrUnsupportedMediaType
+	}
 	if err != nil {
 		return err
 	}
 
-	err = echo.FormFieldBinder(c).
-		String("title", &title).
-		String("url", &url).
-		Bool("archived", &archived).
-		Strings("tags", &tags).
+	err = validate(
+		validateTitle(patch.Title),
+		validateURL(patch.URL),
+		validateDescription(patch.Description),
+	)
+	if err != nil {
+		return err
+	}
+
+	b, err := a.store.Update(c.Request().Context(), *patch)
+	if err != nil {
+		return fail(err)
+	}
+
+	return c.JSON(http.StatusOK, b)
+}
+
+// Refresh fetches the bookmarked page again and stores its metadata. The
+// title and description are filled in from it only if they were never set,
+// so nothing written by hand is replaced.
+func (a *bookmarksAPI) Refresh(c echo.Context) error {
+	var id int64
+
+	err := echo.PathParamsBinder(c).
+		MustInt64("id", &id).
 		BindError()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
 	}
 
-	err = a.store.Update(bookmark.BookmarkPatch{
-		ID:       id,
-		Title:    &title,
-		URL:      &url,
-		Archived: &archived,
-		Tags:     tags,
+	b, err := a.store.Get(c.Request().Context(), id)
+	if err != nil {
+		return fail(err)
+	}
+
+	meta, err := a.fetcher.Fetch(c.Request().Context(), b.URL)
+	if err != nil {
+		return fail(err)
+	}
+
+	title, description := b.Title, b.Description
+	if title == b.URL {
+		title = ""
+	}
+	title, description = fillFromMetadata(b.URL, title, description, meta)
+
+	b, err = a.store.Update(c.Request().Context(), bookmark.BookmarkPatch{
+		ID:          id,
+		Title:       &title,
+		Description: &description,
+		Metadata:    meta,
 	})
 	if err != nil {
 		return fail(err)
 	}
 
-	return c.NoContent(http.StatusOK)
+	return c.JSON(http.StatusOK, b)
+}
+
+// fillFromMetadata returns the title and description for a bookmark, using
+// the page's own where they are empty. Without a title from either, the
+// bookmark is titled with its URL. meta may be nil.
+func fillFromMetadata(u string, title string, description string, meta *metadata.Metadata) (string, string) {
+	if strings.TrimSpace(title) == "" && meta != nil {
+		title = meta.Title
+	}
+	if strings.TrimSpace(title) == "" {
+		title = u
+	}
+
+	if strings.TrimSpace(description) == "" && meta != nil {
+		description = truncate(meta.Description, bookmark.MaxDescriptionLength)
+	}
+
+	return title, description
+}
+
+// truncate shortens s to at most n characters, ending it with an ellipsis
+// if anything was cut.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+// bindFormPatch builds a patch from only the form fields present in the
+// request. Since an empty list can't be sent as form fields, a single empty
+// tags field clears the bookmark's tags.
+func bindFormPatch(c echo.Context, id int64) (*bookmark.BookmarkPatch, error) {
+	params, err := c.FormParams()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	patch := &bookmark.BookmarkPatch{ID: id}
+	b := echo.FormFieldBinder(c)
+
+	if params.Has("title") {
+		patch.Title = new(string)
+		b.String("title", patch.Title)
+	}
+
+	if params.Has("url") {
+		patch.URL = new(string)
+		b.String("url", patch.URL)
+	}
+
+	if params.Has("description") {
+		patch.Description = new(string)
+		b.String("description", patch.Description)
+	}
+
+	if params.Has("notes") {
+		patch.Notes = new(string)
+		b.String("notes", patch.Notes)
+	}
+
+	if params.Has("archived") {
+		patch.Archived = new(bool)
+		b.Bool("archived", patch.Archived)
+	}
+
+	if params.Has("tags") {
+		patch.Tags = tag.Tags{}
+		for _, t := range params["tags"] {
+			if t != "" {
+				patch.Tags = append(patch.Tags, t)
+			}
+		}
+	}
+
+	err = b.BindError()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	return patch, nil
+}
+
+// patchDocument is the editable subset of a bookmark that JSON patches are
+// applied to.
+type patchDocument struct {
+	Title       *string   `json:"title"`
+	URL         *string   `json:"url"`
+	Description *string   `json:"description"`
+	Notes       *string   `json:"notes"`
+	Archived    *bool     `json:"archived"`
+	Tags        *[]string `json:"tags"`
+}
+
+// bindJSONPatch applies a JSON patch from the request body to the current
+// state of the bookmark and returns a patch of the fields that changed.
+func (a *bookmarksAPI) bindJSONPatch(c echo.Context, id int64, apply func(doc []byte, patch []byte) ([]byte, error)) (*bookmark.BookmarkPatch, error) {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	current, err := a.store.Get(c.Request().Context(), id)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	tags := []string(current.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	doc, err := json.Marshal(patchDocument{
+		Title:       &current.Title,
+		URL:         &current.URL,
+		Description: &current.Description,
+		Notes:       &current.Notes,
+		Archived:    &current.Archived,
+		Tags:        &tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = apply(doc, body)
+	if err != nil {
+		if jsonpatch.IsPatchError(err) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error()).WithInternal(err)
+		}
+		return nil, err
+	}
+
+	var updated patchDocument
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.DisallowUnknownFields()
+	err = d.Decode(&updated)
+	if err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			return nil, newValidationError(&fieldError{Field: te.Field, Message: "must be of type " + jsonTypeName(te.Type)})
+		}
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "patch produced an invalid bookmark: "+err.Error()).WithInternal(err)
+	}
+
+	var errs []*fieldError
+	if updated.Title == nil {
+		errs = append(errs, &fieldError{Field: "title", Message: "cannot be removed"})
+	}
+	if updated.URL == nil {
+		errs = append(errs, &fieldError{Field: "url", Message: "cannot be removed"})
+	}
+	if errs != nil {
+		return nil, newValidationError(errs...)
+	}
+
+	// the patch was applied to current, so it only holds if nothing has
+	// changed the bookmark since
+	patch := &bookmark.BookmarkPatch{ID: id, UnmodifiedSince: &current.UpdatedAt}
+	if *updated.Title != current.Title {
+		patch.Title = updated.Title
+	}
+
+	if *updated.URL != current.URL {
+		patch.URL = updated.URL
+	}
+
+	// removing the description or notes clears them
+	if updated.Description == nil {
+		updated.Description = new(string)
+	}
+	if *updated.Description != current.Description {
+		patch.Description = updated.Description
+	}
+
+	if updated.Notes == nil {
+		updated.Notes = new(string)
+	}
+	if *updated.Notes != current.Notes {
+		patch.Notes = updated.Notes
+	}
+
+	if updated.Archived == nil {
+		updated.Archived = new(bool)
+	}
+	if *updated.Archived != current.Archived {
+		patch.Archived = updated.Archived
+	}
+
+	if updated.Tags == nil {
+		updated.Tags = &[]string{}
+	}
+	if !slices.Equal(*updated.Tags, current.Tags) {
+		patch.Tags = tag.Tags(*updated.Tags)
+		if patch.Tags == nil {
+			patch.Tags = tag.Tags{}
+		}
+	}
+
+	return patch, nil
 }
 
 func (a *bookmarksAPI) List(c echo.Context) error {
-	var page, pageSize uint64
+	page, pageSize, err := bindPageParams(c)
+	if err != nil {
+		return err
+	}
+
+	if q := c.QueryParam("q"); q != "" {
+		pq, err := query.Parse(q)
+		if err != nil {
+			return fail(err)
+		}
+
+		rp, err := a.store.Search(c.Request().Context(), pq, page, pageSize)
+		if err != nil {
+			return fail(err)
+		}
+
+		setPageLinks(c, rp.Page, rp.TotalPages)
+		return c.JSON(http.StatusOK, rp)
+	}
+
+	filter, err := bindBookmarkFilter(c, bookmark.StateActive)
+	if err != nil {
+		return err
+	}
+
+	sort, err := bindSort(c)
+	if err != nil {
+		return err
+	}
+
+	if c.QueryParams().Has("cursor") {
+		return a.listByCursor(c, pageSize, filter, sort)
+	}
+
+	bp, err := a.store.GetPage(c.Request().Context(), page, pageSize, filter, sort)
+	if err != nil {
+		return fail(err)
+	}
+
+	setPageLinks(c, bp.Page, bp.TotalPages)
+	return c.JSON(http.StatusOK, bp)
+}
+
+// listByCursor lists bookmarks a page at a time with the opaque cursors
+// returned in each page, starting from the first page when the cursor
+// parameter is empty. The total count is left out when total is false.
+// Cursors only work in creation order.
+func (a *bookmarksAPI) listByCursor(c echo.Context, pageSize uint64, filter bookmark.Filter, sort bookmark.Sort) error {
+	if sort.Field != bookmark.SortCreated {
+		return newValidationError(&fieldError{Field: "sort", Message: "must be created when paging with a cursor"})
+	}
+
+	params := pagination.CursorParams{PageSize: pageSize, Desc: sort.Desc}
+
+	withTotal := true
 	err := echo.QueryParamsBinder(c).
-		Uint64("page", &page).
-		Uint64("pageSize", &pageSize).
+		Bool("total", &withTotal).
 		BindError()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
 	}
+	params.SkipTotal = !withTotal
 
-	if page == 0 {
-		page = 1
+	if s := c.QueryParam("cursor"); s != "" {
+		params.Cursor, err = pagination.ParseCursor(s)
+		if err != nil {
+			return fail(err)
+		}
 	}
 
-	if pageSize == 0 {
-		pageSize = 25
-	} else if pageSize > 100 {
-		pageSize = 100
+	cp, err := a.store.GetCursorPage(c.Request().Context(), params, filter)
+	if err != nil {
+		return fail(err)
 	}
 
-	bp, err := a.store.GetPage(page, pageSize)
+	setCursorLinks(c, cp.Prev, cp.Next)
+	return c.JSON(http.StatusOK, cp)
+}
+
+func (a *bookmarksAPI) Search(c echo.Context) error {
+	var q string
+	err := echo.QueryParamsBinder(c).
+		MustString("q", &q).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required").WithInternal(err)
+	}
+
+	page, pageSize, err := bindPageParams(c)
+	if err != nil {
+		return err
+	}
+
+	pq, err := query.Parse(q)
 	if err != nil {
 		return fail(err)
 	}
 
-	return c.JSON(http.StatusOK, bp)
+	rp, err := a.store.Search(c.Request().Context(), pq, page, pageSize)
+	if err != nil {
+		return fail(err)
+	}
+
+	setPageLinks(c, rp.Page, rp.TotalPages)
+	return c.JSON(http.StatusOK, rp)
 }
 
 func (a *bookmarksAPI) Delete(c echo.Context) error {
@@ -133,7 +513,7 @@ func (a *bookmarksAPI) Delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
 	}
 
-	err = a.store.Delete(id)
+	err = a.store.Delete(c.Request().Context(), id)
 	if err != nil {
 		return fail(err)
 	}
@@ -141,14 +521,277 @@ func (a *bookmarksAPI) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+// mediaType returns the media type of the request body, without any
+// parameters.
+func mediaType(c echo.Context) string {
+	mt, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return mt
+}
+
+// bindBody binds a JSON or form request body into v. JSON bodies are
+// decoded into v directly, while form bodies are bound by bindForm.
+func bindBody(c echo.Context, v any, bindForm func(b *echo.ValueBinder)) error {
+	if mediaType(c) == echo.MIMEApplicationJSON {
+		return decodeJSON(c.Request().Body, v)
+	}
+
+	b := echo.FormFieldBinder(c)
+	bindForm(b)
+
+	err := b.BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a JSON request body into v, reporting values of the
+// wrong type as field errors.
+func decodeJSON(r io.Reader, v any) error {
+	err := json.NewDecoder(r).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return newValidationError(&fieldError{Field: te.Field, Message: "must be of type " + jsonTypeName(te.Type)})
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, "request body is not valid JSON").WithInternal(err)
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "number"
+	}
+}
+
+// validate returns a validation error describing every non-nil field error,
+// or nil if there are none.
+func validate(errs ...*fieldError) error {
+	var invalid validationErrors
+	for _, e := range errs {
+		if e != nil {
+			invalid = append(invalid, e)
+		}
+	}
+
+	if invalid == nil {
+		return nil
+	}
+
+	return newValidationError(invalid...)
+}
+
+// validateTitle checks a title that is being set. A nil title is not being
+// set, so it is always valid.
+func validateTitle(title *string) *fieldError {
+	if title != nil && strings.TrimSpace(*title) == "" {
+		return &fieldError{Field: "title", Message: "is required"}
+	}
+
+	return nil
+}
+
+// validateURL checks a URL that is being set. A nil URL is not being set, so
+// it is always valid.
+func validateURL(u *string) *fieldError {
+	if u == nil {
+		return nil
+	}
+
+	if *u == "" {
+		return &fieldError{Field: "url", Message: "is required"}
+	}
+
+	parsed, err := url.Parse(*u)
+	if err != nil || !parsed.IsAbs() {
+		return &fieldError{Field: "url", Message: "must be an absolute URL"}
+	}
+
+	return nil
+}
+
+// validateDescription checks a description that is being set. A nil
+// description is not being set, so it is always valid.
+func validateDescription(d *string) *fieldError {
+	if d != nil && utf8.RuneCountInString(*d) > bookmark.MaxDescriptionLength {
+		return &fieldError{Field: "description", Message: fmt.Sprintf("must be at most %d characters", bookmark.MaxDescriptionLength)}
+	}
+
+	return nil
+}
+
+func bindPageParams(c echo.Context) (page uint64, pageSize uint64, err error) {
+	err = echo.QueryParamsBinder(c).
+		Uint64("page", &page).
+		Uint64("pageSize", &pageSize).
+		BindError()
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	if page == 0 {
+		page = 1
+	}
+
+	if pageSize == 0 {
+		pageSize = 25
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	return page, pageSize, nil
+}
+
+// bindBookmarkFilter reads the filters for a list of bookmarks from the
+// query parameters: any number of tag parameters, matched according to
+// tagMatch (any or all), archived (true, false, or any), domain, status
+// (unchecked, ok, redirected, or broken), and createdAfter and
+// createdBefore, which are dates or RFC 3339 times.
+func bindBookmarkFilter(c echo.Context, state bookmark.State) (bookmark.Filter, error) {
+	filter := bookmark.Filter{
+		Tags:   slices.DeleteFunc(slices.Clone(c.QueryParams()["tag"]), func(t string) bool { return t == "" }),
+		State:  state,
+		Domain: c.QueryParam("domain"),
+	}
+
+	switch m := bookmark.TagMatch(c.QueryParam("tagMatch")); m {
+	case "", bookmark.TagMatchAny, bookmark.TagMatchAll:
+		filter.TagMatch = m
+	default:
+		return filter, newValidationError(&fieldError{Field: "tagMatch", Message: "must be any or all"})
+	}
+
+	switch v := c.QueryParam("archived"); v {
+	case "":
+	case "any":
+		filter.State = bookmark.StateAll
+	default:
+		archived, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, newValidationError(&fieldError{Field: "archived", Message: "must be true, false, or any"}).WithInternal(err)
+		}
+
+		filter.State = bookmark.StateActive
+		if archived {
+			filter.State = bookmark.StateArchived
+		}
+	}
+
+	var err error
+	if v := c.QueryParam("status"); v != "" {
+		filter.LinkStatus, err = bookmark.ParseLinkStatus(v)
+		if err != nil {
+			return filter, newValidationError(&fieldError{Field: "status", Message: "must be unchecked, ok, redirected, or broken"}).WithInternal(err)
+		}
+	}
+
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"createdAfter", &filter.CreatedAfter},
+		{"createdBefore", &filter.CreatedBefore},
+	} {
+		v := c.QueryParam(p.name)
+		if v == "" {
+			continue
+		}
+
+		*p.t, err = parseTimeParam(v)
+		if err != nil {
+			return filter, newValidationError(&fieldError{Field: p.name, Message: "must be a date (YYYY-MM-DD) or an RFC 3339 time"}).WithInternal(err)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam parses a date, at midnight local time, or an RFC 3339 time.
+func parseTimeParam(v string) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
+
+// bindSort reads the order of a list of bookmarks from the sort (created,
+// updated, title, or domain) and order (asc or desc) query parameters.
+func bindSort(c echo.Context) (bookmark.Sort, error) {
+	var sort bookmark.Sort
+
+	field, err := bookmark.ParseSortField(c.QueryParam("sort"))
+	if err != nil {
+		return sort, newValidationError(&fieldError{Field: "sort", Message: "must be created, updated, title, or domain"}).WithInternal(err)
+	}
+	sort.Field = field
+
+	switch c.QueryParam("order") {
+	case "", "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		return sort, newValidationError(&fieldError{Field: "order", Message: "must be asc or desc"})
+	}
+
+	return sort, nil
+}
+
 func fail(err error) *echo.HTTPError {
 	if bookmark.IsNotFound(err) {
 		return echo.NewHTTPError(http.StatusNotFound, "bookmark not found").WithInternal(err)
 	}
 
+	if bookmark.IsModified(err) {
+		return echo.NewHTTPError(http.StatusConflict, "the bookmark was changed by another request; fetch it and try again").WithInternal(err)
+	}
+
+	if pagination.IsInvalidCursor(err) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor").WithInternal(err)
+	}
+
+	var se *query.SyntaxError
+	if errors.As(err, &se) {
+		return echo.NewHTTPError(http.StatusBadRequest, se.Error()).WithInternal(err)
+	}
+
+	var ie *tag.InvalidError
+	if errors.As(err, &ie) {
+		return newValidationError(&fieldError{Field: "tags", Message: ie.Error()}).WithInternal(err)
+	}
+
+	var mue *metadata.URLError
+	if errors.As(err, &mue) {
+		return newValidationError(&fieldError{Field: "url", Message: mue.Err.Error()}).WithInternal(err)
+	}
+
+	var fe *metadata.FetchError
+	if errors.As(err, &fe) {
+		return echo.NewHTTPError(http.StatusBadGateway, fe.Error()).WithInternal(err)
+	}
+
 	var ue *bookmark.URLExistsError
 	if errors.As(err, &ue) {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("a bookmark already exists with that URL (%s)", ue.URL)).WithInternal(err)
+		return newValidationError(&fieldError{
+			Field:   "url",
+			Message: fmt.Sprintf("a bookmark already exists with that URL (%s)", ue.URL),
+		}).WithInternal(err)
 	}
 
 	return echo.NewHTTPError(http.StatusInternalServerError).WithInternal(err)
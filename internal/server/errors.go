@@ -48,7 +48,19 @@ func customHTTPErrorHandler(err error, c echo.Context) {
 	}
 
 	if strings.HasPrefix(c.Request().URL.Path, "/api/") {
-		c.Echo().DefaultHTTPErrorHandler(err, c)
+		p := newProblem(err, c.Echo().Debug)
+		p.Instance = c.Request().URL.Path
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(p.Status)
+		} else {
+			c.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJSON)
+			err = c.JSON(p.Status, p)
+		}
+
+		if err != nil {
+			c.Logger().Error(fmt.Errorf("while writing problem details, encountered another error: %w", err))
+		}
 		return
 	}
 
@@ -64,3 +76,79 @@ func customHTTPErrorHandler(err error, c echo.Context) {
 		c.Logger().Error(fmt.Errorf("while rendering error page, encountered another error: %w", err))
 	}
 }
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// problem is an RFC 7807 problem details object, which the API responds with
+// for every error.
+type problem struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Status   int           `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Errors   []*fieldError `json:"errors,omitempty"`
+}
+
+// fieldError describes why a single field of a request was rejected.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationErrors is used as the message of an *echo.HTTPError to report
+// every invalid field at once.
+type validationErrors []*fieldError
+
+func (v validationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func newValidationError(errs ...*fieldError) *echo.HTTPError {
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, validationErrors(errs))
+}
+
+func newProblem(err error, debug bool) *problem {
+	var h *echo.HTTPError
+	var be *echo.BindingError
+	if !errors.As(err, &h) {
+		if errors.As(err, &be) {
+			h = be.HTTPError
+		} else {
+			h = echo.NewHTTPError(http.StatusInternalServerError).WithInternal(err)
+		}
+	}
+
+	p := &problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(h.Code),
+		Status: h.Code,
+	}
+
+	switch m := h.Message.(type) {
+	case validationErrors:
+		p.Detail = "The request contains invalid fields."
+		p.Errors = m
+	case string:
+		if m != p.Title {
+			p.Detail = m
+		}
+	case error:
+		p.Detail = m.Error()
+	}
+
+	if p.Errors == nil && (be != nil || errors.As(h.Internal, &be)) {
+		p.Errors = []*fieldError{{Field: be.Field, Message: fmt.Sprint(be.Message)}}
+	}
+
+	if debug && h.Internal != nil && p.Detail == "" {
+		p.Detail = h.Internal.Error()
+	}
+
+	return p
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/exporter"
+	"github.com/labstack/echo/v4"
+)
+
+type exportAPI struct {
+	store bookmark.BookmarkStore
+}
+
+// Netscape streams bookmarks as a Netscape bookmark file. By default every
+// bookmark is exported; the tag and archived parameters narrow them down.
+func (a *exportAPI) Netscape(c echo.Context) error {
+	filter, err := bindExportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="bookmarks.html"`)
+	res.WriteHeader(http.StatusOK)
+
+	err = exporter.ExportNetscape(c.Request().Context(), res, a.store, filter)
+	if err != nil {
+		// the response has already started, so all we can do is log it
+		c.Logger().Error(err)
+	}
+
+	return nil
+}
+
+// bindExportFilter reads the same filters as the bookmark list, except that
+// archived bookmarks are included unless archived is false.
+func bindExportFilter(c echo.Context) (bookmark.Filter, error) {
+	return bindBookmarkFilter(c, bookmark.StateAll)
+}
@@ -5,51 +5,78 @@ import (
 
 	"github.com/cmessinides/mnemonic/internal/bookmark"
 	"github.com/cmessinides/mnemonic/internal/pagination"
+	"github.com/cmessinides/mnemonic/internal/tag"
 	"github.com/labstack/echo/v4"
 )
 
 type homeController struct {
 	bookmarks bookmark.BookmarkStore
+	tags      tag.TagStore
+}
+
+type homeData struct {
+	View           string
+	Tag            string
+	Bookmarks      *pagination.Page[*bookmark.Bookmark]
+	BookmarksError string
+	Tags           []*tag.Node
+	TagsError      string
 }
 
 func (h *homeController) Show(c echo.Context) error {
-	status := http.StatusOK
-	var data struct {
-		View           string
-		Bookmarks      *pagination.Page[*bookmark.Bookmark]
-		BookmarksError string
-	}
-	data.View = "home"
+	data := &homeData{View: "home"}
+	status := h.loadBookmarks(c, data)
 
-	bookmarks, err := h.bookmarks.GetPage(1, 10)
+	tags, err := h.tags.Tree(c.Request().Context())
 	if err != nil {
 		c.Logger().Warn(err)
 		status = http.StatusInternalServerError
-		data.BookmarksError = err.Error()
+		data.TagsError = err.Error()
 	} else {
-		data.Bookmarks = bookmarks
+		data.Tags = tags
 	}
 
 	return c.Render(status, "home.html", data)
 }
 
 func (h *homeController) ShowBookmarks(c echo.Context) error {
-	status := http.StatusOK
-	var data struct {
-		View           string
-		Bookmarks      *pagination.Page[*bookmark.Bookmark]
-		BookmarksError string
+	data := &homeData{View: "home"}
+	status := h.loadBookmarks(c, data)
+
+	return c.Render(status, "home.html#bookmarks", data)
+}
+
+// homePageSize is the number of bookmarks on each page of the home page.
+const homePageSize = 10
+
+// loadBookmarks fills in the bookmarks section, filtered by the tag query
+// parameter and paged by the page query parameter, and returns the status
+// to render it with.
+func (h *homeController) loadBookmarks(c echo.Context, data *homeData) int {
+	data.Tag = c.QueryParam("tag")
+
+	var page uint64
+	err := echo.QueryParamsBinder(c).
+		Uint64("page", &page).
+		BindError()
+	if err != nil {
+		data.BookmarksError = "the page must be a positive number"
+		return http.StatusBadRequest
+	}
+	page = max(page, 1)
+
+	var filter bookmark.Filter
+	if data.Tag != "" {
+		filter.Tags = []string{data.Tag}
 	}
-	data.View = "home"
 
-	bookmarks, err := h.bookmarks.GetPage(1, 10)
+	bookmarks, err := h.bookmarks.GetPage(c.Request().Context(), page, homePageSize, filter, bookmark.Sort{})
 	if err != nil {
 		c.Logger().Warn(err)
-		status = http.StatusInternalServerError
 		data.BookmarksError = err.Error()
-	} else {
-		data.Bookmarks = bookmarks
+		return http.StatusInternalServerError
 	}
 
-	return c.Render(status, "home.html#bookmarks", data)
+	data.Bookmarks = bookmarks
+	return http.StatusOK
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/importer"
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
+	"github.com/labstack/echo/v4"
+)
+
+type importAPI struct {
+	store    bookmark.BookmarkStore
+	tagRules tag.Rules
+	urlRules urlnorm.Rules
+}
+
+// Import imports a file in the format named by the :format path parameter,
+// uploaded either as the file field of a multipart form or as the request
+// body. The duplicates parameter decides what happens to bookmarks that
+// already exist, and the dryRun parameter reports what would change without
+// changing anything.
+func (a *importAPI) Import(c echo.Context) error {
+	format, err := importer.ParseFormat(c.Param("format"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown import format").WithInternal(err)
+	}
+
+	opts := importer.Options{TagRules: a.tagRules, URLRules: a.urlRules}
+	opts.Duplicates, err = importer.ParseDuplicatePolicy(importParam(c, "duplicates"))
+	if err != nil {
+		return newValidationError(&fieldError{Field: "duplicates", Message: "must be one of skip, merge, or overwrite"}).WithInternal(err)
+	}
+
+	if v := importParam(c, "dryRun"); v != "" {
+		opts.DryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			return newValidationError(&fieldError{Field: "dryRun", Message: "must be true or false"}).WithInternal(err)
+		}
+	}
+
+	body, err := importFile(c)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	items, err := importer.Parse(format, body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "could not read bookmark file").WithInternal(err)
+	}
+
+	report, err := importer.NewImporter(a.store, opts).Import(c.Request().Context(), items)
+	if err != nil {
+		return fail(err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// importParam returns a query parameter, or a field of a multipart form.
+// Other request bodies are the file being imported, so aren't parsed as a
+// form.
+func importParam(c echo.Context, name string) string {
+	if mediaType(c) == echo.MIMEMultipartForm {
+		return c.FormValue(name)
+	}
+
+	return c.QueryParam(name)
+}
+
+// importFile returns the uploaded file of a multipart form, or the request
+// body for any other request.
+func importFile(c echo.Context) (io.ReadCloser, error) {
+	if mediaType(c) != echo.MIMEMultipartForm {
+		return c.Request().Body, nil
+	}
+
+	fh, err := c.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, newValidationError(&fieldError{Field: "file", Message: "is required"}).WithInternal(err)
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest).WithInternal(err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/cmessinides/mnemonic/internal/linkcheck"
+	"github.com/labstack/echo/v4"
+)
+
+type linkChecksAPI struct {
+	store linkcheck.Store
+}
+
+// List returns the recent link checks of a bookmark, newest first.
+func (a *linkChecksAPI) List(c echo.Context) error {
+	var id int64
+
+	err := echo.PathParamsBinder(c).
+		MustInt64("id", &id).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required").WithInternal(err)
+	}
+
+	checks, err := a.store.List(c.Request().Context(), id)
+	if err != nil {
+		return fail(err)
+	}
+
+	return c.JSON(http.StatusOK, checks)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// link is one link of a Link header, to the current request with the given
+// query parameters changed.
+type link struct {
+	rel    string
+	params map[string]string
+}
+
+// setLinks sets the Link header (RFC 8288) of the response. Targets are
+// relative to the request, so they resolve against whatever host and scheme
+// the client used.
+func setLinks(c echo.Context, links ...link) {
+	if len(links) == 0 {
+		return
+	}
+
+	values := make([]string, 0, len(links))
+	for _, l := range links {
+		// QueryParams is cached by the context, so it mustn't be changed
+		q := maps.Clone(c.QueryParams())
+		for k, v := range l.params {
+			q.Set(k, v)
+		}
+
+		target := c.Request().URL.Path + "?" + q.Encode()
+		values = append(values, "<"+target+`>; rel="`+l.rel+`"`)
+	}
+
+	c.Response().Header().Set("Link", strings.Join(values, ", "))
+}
+
+// setPageLinks links to the first, previous, next, and last pages of an
+// offset-paginated list.
+func setPageLinks(c echo.Context, page uint64, totalPages uint64) {
+	pageLink := func(rel string, p uint64) link {
+		return link{rel: rel, params: map[string]string{"page": strconv.FormatUint(p, 10)}}
+	}
+
+	links := []link{pageLink("first", 1)}
+	if page > 1 {
+		links = append(links, pageLink("prev", min(page-1, totalPages)))
+	}
+	if page < totalPages {
+		links = append(links, pageLink("next", page+1))
+	}
+	links = append(links, pageLink("last", totalPages))
+
+	setLinks(c, links...)
+}
+
+// setCursorLinks links to the first, previous, and next pages of a
+// cursor-paginated list.
+func setCursorLinks(c echo.Context, prev string, next string) {
+	links := []link{{rel: "first", params: map[string]string{"cursor": ""}}}
+	if prev != "" {
+		links = append(links, link{rel: "prev", params: map[string]string{"cursor": prev}})
+	}
+	if next != "" {
+		links = append(links, link{rel: "next", params: map[string]string{"cursor": next}})
+	}
+
+	setLinks(c, links...)
+}
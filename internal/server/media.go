@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/cmessinides/mnemonic/internal/media"
+	"github.com/labstack/echo/v4"
+)
+
+type mediaController struct {
+	cache *media.Cache
+}
+
+// Show serves an image from the media cache. A cached image never changes,
+// since its name is the hash of its contents, so browsers can keep it for
+// as long as they like. A missing image might be cached later, so its 404
+// isn't kept.
+func (m *mediaController) Show(c echo.Context) error {
+	name := c.Param("name")
+	if !media.ValidName(name) {
+		return echo.ErrNotFound
+	}
+
+	path := m.cache.Path(name)
+	_, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	return c.File(path)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/cmessinides/mnemonic/internal/bookmark"
+	"github.com/cmessinides/mnemonic/internal/pagination"
+	"github.com/cmessinides/mnemonic/internal/query"
+	"github.com/labstack/echo/v4"
+)
+
+type searchController struct {
+	bookmarks bookmark.BookmarkStore
+}
+
+func (s *searchController) Show(c echo.Context) error {
+	status := http.StatusOK
+	var data struct {
+		View         string
+		Query        string
+		Results      *pagination.Page[*bookmark.SearchResult]
+		ResultsError string
+	}
+	data.View = "search"
+
+	page, pageSize, err := bindPageParams(c)
+	if err != nil {
+		return err
+	}
+
+	data.Query = c.QueryParam("q")
+	q, err := query.Parse(data.Query)
+	if err != nil {
+		status = http.StatusBadRequest
+		data.ResultsError = err.Error()
+		return c.Render(status, "search.html", data)
+	}
+
+	results, err := s.bookmarks.Search(c.Request().Context(), q, page, pageSize)
+	if err != nil {
+		c.Logger().Warn(err)
+		status = http.StatusInternalServerError
+		data.ResultsError = err.Error()
+	} else {
+		data.Results = results
+	}
+
+	return c.Render(status, "search.html", data)
+}
@@ -1,11 +1,22 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/cmessinides/mnemonic/internal/backup"
 	"github.com/cmessinides/mnemonic/internal/bookmark"
 	"github.com/cmessinides/mnemonic/internal/config"
+	"github.com/cmessinides/mnemonic/internal/jobs"
+	"github.com/cmessinides/mnemonic/internal/linkcheck"
+	"github.com/cmessinides/mnemonic/internal/media"
+	"github.com/cmessinides/mnemonic/internal/metadata"
+	"github.com/cmessinides/mnemonic/internal/tag"
 	"github.com/cmessinides/mnemonic/internal/ui"
+	"github.com/cmessinides/mnemonic/internal/urlnorm"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -19,9 +30,11 @@ type Config struct {
 	config.ServerConfig
 	Dev       bool
 	LookupEnv config.LookupEnv
+	TagRules  tag.Rules
+	URLRules  urlnorm.Rules
 }
 
-func NewServer(conf *Config, bookmarks bookmark.BookmarkStore) *Server {
+func NewServer(conf *Config, bookmarks bookmark.BookmarkStore, tags tag.TagStore, backups *backup.Manager, fetcher *metadata.Fetcher, cache *media.Cache, checks linkcheck.Store, queue jobs.Store) *Server {
 	e := echo.New()
 	e.HideBanner = true
 	e.Debug = conf.Dev
@@ -29,6 +42,13 @@ func NewServer(conf *Config, bookmarks bookmark.BookmarkStore) *Server {
 		Format: `{"method":"${method}","uri":"${uri}","status":"${status}"}` + "\n",
 	}))
 	e.HTTPErrorHandler = customHTTPErrorHandler
+	if conf.QueryTimeout > 0 {
+		e.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+			Skipper:      skipQueryTimeout,
+			ErrorHandler: queryTimeoutErrorHandler,
+			Timeout:      time.Duration(conf.QueryTimeout),
+		}))
+	}
 
 	u := ui.NewUI(ui.UIConfig{
 		Dev:       conf.Dev,
@@ -39,18 +59,54 @@ func NewServer(conf *Config, bookmarks bookmark.BookmarkStore) *Server {
 	e.RouteNotFound("/*", customNotFoundHandler)
 	e.RouteNotFound("/api/*", apiNotFoundHandler)
 
-	h := &homeController{bookmarks: bookmarks}
+	h := &homeController{bookmarks: bookmarks, tags: tags}
 	e.GET("/", h.Show)
 	e.GET("/_views/bookmarks", h.ShowBookmarks)
 
+	sc := &searchController{bookmarks: bookmarks}
+	e.GET("/search", sc.Show)
+
+	bc := &bookmarkController{bookmarks: bookmarks}
+	e.GET("/bookmarks/:id", bc.Show)
+
+	mc := &mediaController{cache: cache}
+	e.GET("/media/:name", mc.Show)
+
 	api := e.Group("/api/v1")
 
-	b := &bookmarksAPI{store: bookmarks}
+	b := &bookmarksAPI{store: bookmarks, fetcher: fetcher}
 	api.GET("/bookmarks", b.List)
 	api.POST("/bookmarks", b.Create)
+	api.GET("/bookmarks/search", b.Search)
 	api.GET("/bookmarks/:id", b.Read)
 	api.PATCH("/bookmarks/:id", b.Update)
 	api.DELETE("/bookmarks/:id", b.Delete)
+	api.POST("/bookmarks/:id/refresh", b.Refresh)
+
+	lc := &linkChecksAPI{store: checks}
+	api.GET("/bookmarks/:id/checks", lc.List)
+
+	t := &tagsAPI{store: tags}
+	api.GET("/tags", t.List)
+	api.GET("/tags/:name", t.Read)
+	api.PATCH("/tags/:name", t.Rename)
+	api.POST("/tags/:name/merge", t.Merge)
+	api.DELETE("/tags/:name", t.Delete)
+	api.GET("/tags/:name/aliases", t.ListAliases)
+	api.POST("/tags/:name/aliases", t.AddAlias)
+	api.DELETE("/tags/:name/aliases/:alias", t.RemoveAlias)
+
+	im := &importAPI{store: bookmarks, tagRules: conf.TagRules, urlRules: conf.URLRules}
+	api.POST("/import/:format", im.Import)
+
+	ex := &exportAPI{store: bookmarks}
+	api.GET("/export/netscape", ex.Netscape)
+
+	ad := &adminAPI{backups: backups, jobs: queue}
+	api.POST("/admin/backup", ad.Backup)
+	api.GET("/admin/jobs", ad.ListJobs)
+	api.GET("/admin/jobs/:id", ad.ReadJob)
+	api.POST("/admin/jobs/:id/retry", ad.RetryJob)
 
 	return &Server{
 		config: conf,
@@ -58,6 +114,29 @@ func NewServer(conf *Config, bookmarks bookmark.BookmarkStore) *Server {
 	}
 }
 
+// untimedRoutes are the routes whose work grows with the size of the
+// database, so they aren't cut off by the query timeout.
+var untimedRoutes = map[string]bool{
+	"/api/v1/import/:format":  true,
+	"/api/v1/export/netscape": true,
+	"/api/v1/admin/backup":    true,
+}
+
+func skipQueryTimeout(c echo.Context) bool {
+	return untimedRoutes[c.Path()]
+}
+
+// queryTimeoutErrorHandler reports a request that ran out of time as
+// unavailable. The database driver doesn't always return the context's
+// error when a query is interrupted, so the context is checked directly.
+func queryTimeoutErrorHandler(err error, c echo.Context) error {
+	if errors.Is(c.Request().Context().Err(), context.DeadlineExceeded) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "the request timed out").SetInternal(err)
+	}
+
+	return err
+}
+
 func (s *Server) Start() {
 	address := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 	s.e.Logger.Fatal(s.e.Start(address))
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cmessinides/mnemonic/internal/tag"
+	"github.com/labstack/echo/v4"
+)
+
+type tagsAPI struct {
+	store tag.TagStore
+}
+
+// List returns every tag, or the tag hierarchy when the format query
+// parameter is "tree".
+func (a *tagsAPI) List(c echo.Context) error {
+	if c.QueryParam("format") == "tree" {
+		tree, err := a.store.Tree(c.Request().Context())
+		if err != nil {
+			return failTag(err)
+		}
+
+		return c.JSON(http.StatusOK, tree)
+	}
+
+	tags, err := a.store.List(c.Request().Context())
+	if err != nil {
+		return failTag(err)
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (a *tagsAPI) Read(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	t, err := a.store.Get(c.Request().Context(), name)
+	if err != nil {
+		return failTag(err)
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+// Rename changes the name of a tag to the name given in the request body.
+func (a *tagsAPI) Rename(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	err = bindBody(c, &body, func(b *echo.ValueBinder) {
+		b.String("name", &body.Name)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = validate(validateTagName("name", body.Name))
+	if err != nil {
+		return err
+	}
+
+	t, err := a.store.Rename(c.Request().Context(), name, body.Name)
+	if err != nil {
+		return failTag(err)
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+// Merge replaces a tag with the tag named by into in the request body.
+func (a *tagsAPI) Merge(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Into string `json:"into"`
+	}
+	err = bindBody(c, &body, func(b *echo.ValueBinder) {
+		b.String("into", &body.Into)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = validate(validateTagName("into", body.Into))
+	if err != nil {
+		return err
+	}
+
+	t, err := a.store.Merge(c.Request().Context(), name, body.Into)
+	if err != nil {
+		var ie *tag.InvalidError
+		if errors.As(err, &ie) {
+			return newValidationError(&fieldError{Field: "into", Message: ie.Reason}).WithInternal(err)
+		}
+		return failTag(err)
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func (a *tagsAPI) Delete(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = a.store.Delete(c.Request().Context(), name)
+	if err != nil {
+		return failTag(err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (a *tagsAPI) ListAliases(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	aliases, err := a.store.Aliases(c.Request().Context(), name)
+	if err != nil {
+		return failTag(err)
+	}
+
+	return c.JSON(http.StatusOK, aliases)
+}
+
+// AddAlias makes the alias in the request body another name for the tag.
+func (a *tagsAPI) AddAlias(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Alias string `json:"alias"`
+	}
+	err = bindBody(c, &body, func(b *echo.ValueBinder) {
+		b.String("alias", &body.Alias)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = validate(validateTagName("alias", body.Alias))
+	if err != nil {
+		return err
+	}
+
+	alias, err := a.store.AddAlias(c.Request().Context(), name, body.Alias)
+	if err != nil {
+		return failAlias(err)
+	}
+
+	return c.JSON(http.StatusCreated, alias)
+}
+
+func (a *tagsAPI) RemoveAlias(c echo.Context) error {
+	name, err := tagNameParam(c)
+	if err != nil {
+		return err
+	}
+
+	alias, err := unescapedParam(c, "alias")
+	if err != nil {
+		return err
+	}
+
+	err = a.store.RemoveAlias(c.Request().Context(), name, alias)
+	if err != nil {
+		return failAlias(err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// tagNameParam returns the unescaped :name path parameter.
+func tagNameParam(c echo.Context) (string, error) {
+	return unescapedParam(c, "name")
+}
+
+// unescapedParam returns an unescaped path parameter. Echo matches routes
+// against the raw path when the request path contains escaped characters
+// (such as %2F), leaving the parameters escaped.
+func unescapedParam(c echo.Context, name string) (string, error) {
+	value := c.Param(name)
+	if c.Request().URL.RawPath == "" {
+		return value, nil
+	}
+
+	value, err := url.PathUnescape(value)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s is not properly escaped", name)).WithInternal(err)
+	}
+
+	return value, nil
+}
+
+func validateTagName(field string, name string) *fieldError {
+	if name == "" {
+		return &fieldError{Field: field, Message: "is required"}
+	}
+
+	return nil
+}
+
+func failTag(err error) *echo.HTTPError {
+	if tag.IsNotFound(err) {
+		return echo.NewHTTPError(http.StatusNotFound, "tag not found").WithInternal(err)
+	}
+
+	var ie *tag.InvalidError
+	if errors.As(err, &ie) {
+		return newValidationError(&fieldError{Field: "name", Message: ie.Reason}).WithInternal(err)
+	}
+
+	var ae *tag.AliasExistsError
+	if errors.As(err, &ae) {
+		return echo.NewHTTPError(http.StatusConflict, validationErrors{{
+			Field:   "name",
+			Message: fmt.Sprintf("%s is already an alias of a tag", ae.Alias),
+		}}).WithInternal(err)
+	}
+
+	var te *tag.ExistsError
+	if errors.As(err, &te) {
+		return echo.NewHTTPError(http.StatusConflict, validationErrors{{
+			Field:   "name",
+			Message: fmt.Sprintf("a tag named %s already exists; merge the tags instead", te.Name),
+		}}).WithInternal(err)
+	}
+
+	return echo.NewHTTPError(http.StatusInternalServerError).WithInternal(err)
+}
+
+// failAlias is like failTag, but reports problems with the alias rather than
+// the tag name.
+func failAlias(err error) *echo.HTTPError {
+	if tag.IsAliasNotFound(err) {
+		return echo.NewHTTPError(http.StatusNotFound, "alias not found").WithInternal(err)
+	}
+
+	var ae *tag.AliasExistsError
+	if errors.As(err, &ae) {
+		return echo.NewHTTPError(http.StatusConflict, validationErrors{{
+			Field:   "alias",
+			Message: fmt.Sprintf("%s is already an alias of a tag", ae.Alias),
+		}}).WithInternal(err)
+	}
+
+	var te *tag.ExistsError
+	if errors.As(err, &te) {
+		return echo.NewHTTPError(http.StatusConflict, validationErrors{{
+			Field:   "alias",
+			Message: fmt.Sprintf("a tag named %s already exists; merge the tags instead", te.Name),
+		}}).WithInternal(err)
+	}
+
+	var ie *tag.InvalidError
+	if errors.As(err, &ie) {
+		return newValidationError(&fieldError{Field: "alias", Message: ie.Reason}).WithInternal(err)
+	}
+
+	return failTag(err)
+}
@@ -0,0 +1,16 @@
+// Package sqltime formats times for comparing with those stored by the
+// SQLite driver, which writes them with the offset of their own time zone,
+// so they can only be compared after converting both with julianday().
+package sqltime
+
+import "time"
+
+// layout is a UTC time format julianday() understands. Every digit of the
+// seconds is kept, so that julianday() rounds it the way it rounds the
+// stored time it came from.
+const layout = "2006-01-02 15:04:05.999999999"
+
+// Julian formats t for passing to julianday() in a query.
+func Julian(t time.Time) string {
+	return t.UTC().Format(layout)
+}
@@ -0,0 +1,216 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Alias is another name for a tag, like "golang" for "go". Bookmarks tagged
+// with an alias are given the tag instead.
+type Alias struct {
+	Alias string `json:"alias"`
+	Tag   string `json:"tag"`
+}
+
+// ResolveAliases replaces any aliases in a list of normalized tags with the
+// tags they stand for, dropping the duplicates this creates. Aliases also
+// apply beneath them in the hierarchy, so with "k8s" as an alias of
+// "kubernetes", "k8s/helm" resolves to "kubernetes/helm".
+func ResolveAliases(ctx context.Context, q sqlx.QueryerContext, tags Tags) (Tags, error) {
+	if len(tags) == 0 {
+		return tags, nil
+	}
+
+	names := Tags{}
+	for _, t := range tags {
+		names = append(names, Ancestors(t)...)
+		names = append(names, t)
+	}
+
+	var aliases []*Alias
+	err := sqlx.SelectContext(ctx, q, &aliases, `
+        SELECT a.alias, t.name tag
+        FROM tag_aliases a
+        JOIN tags t ON t.id = a.tag_id
+        WHERE a.alias IN (SELECT value FROM json_each(?))
+    `, names)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve tag aliases: %w", err)
+	}
+
+	if len(aliases) == 0 {
+		return tags, nil
+	}
+
+	byAlias := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		byAlias[a.Alias] = a.Tag
+	}
+
+	resolved := make(Tags, 0, len(tags))
+	for _, t := range tags {
+		t = resolveAlias(t, byAlias)
+		if !slices.Contains(resolved, t) {
+			resolved = append(resolved, t)
+		}
+	}
+
+	return resolved, nil
+}
+
+// resolveAlias replaces the longest aliased prefix of a tag.
+func resolveAlias(name string, byAlias map[string]string) string {
+	if t, ok := byAlias[name]; ok {
+		return t
+	}
+
+	ancestors := Ancestors(name)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if t, ok := byAlias[ancestors[i]]; ok {
+			return t + strings.TrimPrefix(name, ancestors[i])
+		}
+	}
+
+	return name
+}
+
+// Aliases lists the aliases of a tag.
+func (ts *SQLiteTagStore) Aliases(ctx context.Context, name string) ([]*Alias, error) {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := getTag(ctx, ts.db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := []*Alias{}
+	err = ts.db.SelectContext(ctx, &aliases, `
+        SELECT alias, ? tag FROM tag_aliases WHERE tag_id = ? ORDER BY alias
+    `, t.Name, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not select tag aliases: %w", err)
+	}
+
+	return aliases, nil
+}
+
+// AddAlias makes alias another name for a tag, creating the tag if it
+// doesn't exist yet. If name is itself an alias, alias is added to the tag
+// it stands for. It fails with an *ExistsError if alias is already the name
+// of a tag, or an *AliasExistsError if it is already an alias.
+func (ts *SQLiteTagStore) AddAlias(ctx context.Context, name string, alias string) (*Alias, error) {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	alias, err = ts.rules.Normalize(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ts.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tag alias: %w", err)
+	}
+	defer tx.Rollback()
+
+	resolved, err := ResolveAliases(ctx, tx, Tags{name})
+	if err != nil {
+		return nil, err
+	}
+	name = resolved[0]
+
+	if alias == name {
+		return nil, &InvalidError{Name: alias, Reason: "a tag can't be an alias of itself"}
+	}
+
+	_, err = getTag(ctx, tx, alias)
+	if err == nil {
+		return nil, &ExistsError{Name: alias, Err: errors.New("an alias can't be the name of a tag")}
+	}
+	if !IsNotFound(err) {
+		return nil, err
+	}
+
+	var id int64
+	err = tx.GetContext(ctx, &id, `
+        INSERT INTO tags (name) VALUES (?)
+        ON CONFLICT (name) DO UPDATE SET name = excluded.name
+        RETURNING id
+    `, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tag alias: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO tag_aliases (alias, tag_id) VALUES (?, ?)", alias, id)
+	if err != nil {
+		if isDuplicateAlias(err) {
+			return nil, &AliasExistsError{Alias: alias, Err: err}
+		}
+
+		return nil, fmt.Errorf("failed to add tag alias: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tag alias: %w", err)
+	}
+
+	return &Alias{Alias: alias, Tag: name}, nil
+}
+
+// RemoveAlias removes an alias from a tag. Bookmarks that were tagged using
+// the alias keep the tag.
+func (ts *SQLiteTagStore) RemoveAlias(ctx context.Context, name string, alias string) error {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return err
+	}
+
+	alias, err = ts.rules.Normalize(alias)
+	if err != nil {
+		return err
+	}
+
+	result, err := ts.db.ExecContext(ctx, `
+        DELETE FROM tag_aliases
+        WHERE alias = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)
+    `, alias, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag alias: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to remove tag alias: %w", err)
+	}
+
+	if n == 0 {
+		return &AliasNotFoundError{Alias: alias, Tag: name}
+	}
+
+	return nil
+}
+
+func isDuplicateAlias(err error) bool {
+	var sqliteErr *sqlite.Error
+
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return (code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || code == sqlite3.SQLITE_CONSTRAINT_UNIQUE) &&
+			strings.Contains(sqliteErr.Error(), "tag_aliases.alias")
+	}
+
+	return false
+}
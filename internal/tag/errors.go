@@ -0,0 +1,79 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+)
+
+type NotFoundError struct {
+	Name string
+	Err  error
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("no tag found named %s", e.Name)
+	if e.Err != nil {
+		msg = msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+func IsNotFound(err error) bool {
+	var n *NotFoundError
+	return errors.As(err, &n)
+}
+
+type ExistsError struct {
+	Name string
+	Err  error
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("a tag named %s already exists: %s", e.Name, e.Err.Error())
+}
+
+func (e *ExistsError) Unwrap() error {
+	return e.Err
+}
+
+func IsExists(err error) bool {
+	var x *ExistsError
+	return errors.As(err, &x)
+}
+
+type AliasExistsError struct {
+	Alias string
+	Err   error
+}
+
+func (e *AliasExistsError) Error() string {
+	return fmt.Sprintf("%s is already an alias of a tag: %s", e.Alias, e.Err.Error())
+}
+
+func (e *AliasExistsError) Unwrap() error {
+	return e.Err
+}
+
+func IsAliasExists(err error) bool {
+	var x *AliasExistsError
+	return errors.As(err, &x)
+}
+
+type AliasNotFoundError struct {
+	Alias string
+	Tag   string
+}
+
+func (e *AliasNotFoundError) Error() string {
+	return fmt.Sprintf("%s is not an alias of a tag named %s", e.Alias, e.Tag)
+}
+
+func IsAliasNotFound(err error) bool {
+	var n *AliasNotFoundError
+	return errors.As(err, &n)
+}
@@ -0,0 +1,127 @@
+package tag
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rules control how tag names are cleaned up before they are stored, so
+// that variations like "Go" and "go " end up as the same tag.
+type Rules struct {
+	// FoldCase compares and stores tags case-insensitively by folding them
+	// to lower case.
+	FoldCase bool `json:"foldCase"`
+	// TrimSpace removes leading and trailing whitespace from each level of a
+	// tag and collapses runs of whitespace inside it to a single space.
+	TrimSpace bool `json:"trimSpace"`
+	// NFC converts tags to Unicode normalization form C, so that visually
+	// identical tags are stored identically.
+	NFC bool `json:"nfc"`
+	// MaxLength is the maximum length of a tag in characters, or 0 for no
+	// limit.
+	MaxLength int `json:"maxLength"`
+	// AllowedChars lists the characters other than letters and numbers that
+	// may appear in a tag. If empty, any character is allowed.
+	AllowedChars string `json:"allowedChars"`
+	// Dedupe removes repeated tags from a list.
+	Dedupe bool `json:"dedupe"`
+}
+
+var DefaultRules = Rules{
+	FoldCase:     true,
+	TrimSpace:    true,
+	NFC:          true,
+	MaxLength:    64,
+	AllowedChars: " -_.+#&:/",
+	Dedupe:       true,
+}
+
+type InvalidError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("invalid tag %q: %s", e.Name, e.Reason)
+}
+
+func Normalize(name string) (string, error) {
+	return DefaultRules.Normalize(name)
+}
+
+func Parse(names []string) (Tags, error) {
+	return DefaultRules.Parse(names)
+}
+
+// Normalize applies the rules to a single tag name, returning an
+// *InvalidError if the result is empty or breaks a rule.
+func (r *Rules) Normalize(name string) (string, error) {
+	n := name
+
+	if r.NFC {
+		n = norm.NFC.String(n)
+	}
+
+	// drop empty levels from the hierarchy, like the ones in "/work//infra/"
+	segments := strings.Split(n, Separator)
+	n = ""
+	for _, seg := range segments {
+		if r.TrimSpace {
+			seg = strings.Join(strings.Fields(seg), " ")
+		}
+
+		if seg != "" {
+			if n != "" {
+				n += Separator
+			}
+			n += seg
+		}
+	}
+
+	if r.FoldCase {
+		n = cases.Fold().String(n)
+	}
+
+	if n == "" {
+		return "", &InvalidError{Name: name, Reason: "tag is empty"}
+	}
+
+	if r.MaxLength > 0 && utf8.RuneCountInString(n) > r.MaxLength {
+		return "", &InvalidError{Name: name, Reason: fmt.Sprintf("tag is longer than %d characters", r.MaxLength)}
+	}
+
+	if r.AllowedChars != "" {
+		for _, c := range n {
+			if !unicode.In(c, unicode.Letter, unicode.Number, unicode.Mark) && !strings.ContainsRune(r.AllowedChars, c) {
+				return "", &InvalidError{Name: name, Reason: fmt.Sprintf("tag contains disallowed character %q", c)}
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Parse normalizes a list of tag names, stopping at the first invalid one.
+func (r *Rules) Parse(names []string) (Tags, error) {
+	tags := make(Tags, 0, len(names))
+	for _, name := range names {
+		n, err := r.Normalize(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Dedupe && slices.Contains(tags, n) {
+			continue
+		}
+
+		tags = append(tags, n)
+	}
+
+	return tags, nil
+}
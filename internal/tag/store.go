@@ -0,0 +1,456 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type Tag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type TagStore interface {
+	List(ctx context.Context) ([]*Tag, error)
+	Tree(ctx context.Context) ([]*Node, error)
+	Get(ctx context.Context, name string) (*Tag, error)
+	Rename(ctx context.Context, name string, newName string) (*Tag, error)
+	Merge(ctx context.Context, name string, into string) (*Tag, error)
+	Delete(ctx context.Context, name string) error
+	Aliases(ctx context.Context, name string) ([]*Alias, error)
+	AddAlias(ctx context.Context, name string, alias string) (*Alias, error)
+	RemoveAlias(ctx context.Context, name string, alias string) error
+	NormalizeAll(ctx context.Context) ([]*Normalization, error)
+}
+
+// Normalization records the outcome of applying the tag rules to an existing
+// tag.
+type Normalization struct {
+	Name    string
+	NewName string
+	// Merged is set when the normalized name belonged to another tag, or
+	// was an alias of one, which this tag was merged into.
+	Merged bool
+	// Err is set when the tag breaks the rules in a way that can't be fixed
+	// automatically, in which case it is left as is.
+	Err error
+}
+
+func NewSQLiteTagStore(db *sql.DB, rules Rules) *SQLiteTagStore {
+	return &SQLiteTagStore{
+		db:    sqlx.NewDb(db, "sqlite"),
+		rules: rules,
+	}
+}
+
+type SQLiteTagStore struct {
+	db    *sqlx.DB
+	rules Rules
+}
+
+const tagColumns = "t.id, t.name, (SELECT COUNT(1) FROM bookmark_tags bt WHERE bt.tag_id = t.id) count"
+
+func (ts *SQLiteTagStore) List(ctx context.Context) ([]*Tag, error) {
+	tags := []*Tag{}
+	err := ts.db.SelectContext(ctx, &tags, "SELECT "+tagColumns+" FROM tags t ORDER BY t.name")
+	if err != nil {
+		return nil, fmt.Errorf("could not select tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (ts *SQLiteTagStore) Tree(ctx context.Context) ([]*Node, error) {
+	tags, err := ts.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// count the distinct bookmarks beneath every level of every tag, so
+	// that a bookmark tagged with both a tag and its parent counts once
+	var totals []struct {
+		Name  string
+		Total int64
+	}
+	err = ts.db.SelectContext(ctx, &totals, `
+        WITH RECURSIVE levels (tag_id, rest, name) AS (
+            SELECT id, name || ?, '' FROM tags
+            UNION ALL
+            SELECT
+                tag_id,
+                substr(rest, instr(rest, ?) + 1),
+                name || iif(name = '', '', ?) || substr(rest, 1, instr(rest, ?) - 1)
+            FROM levels
+            WHERE rest != ''
+        )
+        SELECT l.name, COUNT(DISTINCT bt.bookmark_id) total
+        FROM levels l
+        JOIN bookmark_tags bt ON bt.tag_id = l.tag_id
+        WHERE l.name != ''
+        GROUP BY l.name
+    `, Separator, Separator, Separator, Separator)
+	if err != nil {
+		return nil, fmt.Errorf("could not count tags: %w", err)
+	}
+
+	byName := make(map[string]int64, len(totals))
+	for _, t := range totals {
+		byName[t.Name] = t.Total
+	}
+
+	return BuildTree(tags, byName), nil
+}
+
+func (ts *SQLiteTagStore) Get(ctx context.Context, name string) (*Tag, error) {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTag(ctx, ts.db, name)
+}
+
+func getTag(ctx context.Context, q sqlx.QueryerContext, name string) (*Tag, error) {
+	t := &Tag{}
+	err := sqlx.GetContext(ctx, q, t, "SELECT "+tagColumns+" FROM tags t WHERE t.name = ?", name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &NotFoundError{Name: name, Err: err}
+		}
+
+		return nil, fmt.Errorf("failed to read tag from database: %w", err)
+	}
+
+	return t, nil
+}
+
+// Rename changes the name of a tag and every tag beneath it on every
+// bookmark that has them. It fails with an *ExistsError if any of the new
+// names are already taken, and an *AliasExistsError if any are aliases;
+// use Merge to combine two existing tags.
+//
+// A tag that only exists as the parent of other tags can be renamed too, in
+// which case the returned tag has no ID and a count of zero.
+func (ts *SQLiteTagStore) Rename(ctx context.Context, name string, newName string) (*Tag, error) {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	newName, err = ts.rules.Normalize(newName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ts.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = renameTag(ctx, tx, name, newName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := getTag(ctx, tx, newName)
+	if IsNotFound(err) {
+		t, err = &Tag{Name: newName}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	return t, nil
+}
+
+func renameTag(ctx context.Context, tx *sqlx.Tx, name string, newName string, descendants bool) error {
+	if descendants && strings.HasPrefix(newName, name+Separator) {
+		return &InvalidError{Name: newName, Reason: "a tag can't be moved beneath itself"}
+	}
+
+	query := "SELECT id, name FROM tags WHERE name = ?"
+	args := []any{name}
+	if descendants {
+		query += " OR substr(name, 1, length(?)) = ?"
+		prefix := name + Separator
+		args = append(args, prefix, prefix)
+	}
+
+	var renamed []struct {
+		ID   int64
+		Name string
+	}
+	err := tx.SelectContext(ctx, &renamed, query, args...)
+	if err != nil {
+		return fmt.Errorf("could not select tags to rename: %w", err)
+	}
+	if len(renamed) == 0 {
+		return &NotFoundError{Name: name}
+	}
+
+	old := make([]string, len(renamed))
+	targets := make([]string, len(renamed))
+	for i, r := range renamed {
+		old[i] = r.Name
+		targets[i] = newName + strings.TrimPrefix(r.Name, name)
+	}
+
+	// the tags being renamed give up their names, so only other tags can
+	// be in the way
+	q, qargs, err := sqlx.In(`
+        SELECT name FROM tags WHERE name IN (?) AND name NOT IN (?) ORDER BY name LIMIT 1
+    `, targets, old)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	var taken string
+	err = tx.GetContext(ctx, &taken, q, qargs...)
+	if err == nil {
+		return &ExistsError{Name: taken, Err: errors.New("the tag is in the way of the rename")}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look for conflicting tags: %w", err)
+	}
+
+	q, qargs, err = sqlx.In("SELECT alias FROM tag_aliases WHERE alias IN (?) ORDER BY alias LIMIT 1", targets)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	err = tx.GetContext(ctx, &taken, q, qargs...)
+	if err == nil {
+		return &AliasExistsError{Alias: taken, Err: errors.New("remove the alias before using it as a tag name")}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look for conflicting tag aliases: %w", err)
+	}
+
+	// names are unique on every row as it's updated, so a tag can't take a
+	// name another renamed tag hasn't given up yet; moving them all to
+	// placeholder names first avoids that. Tag names can't hold NUL.
+	if len(renamed) > 1 {
+		for _, r := range renamed {
+			_, err = tx.ExecContext(ctx, "UPDATE tags SET name = ? WHERE id = ?", fmt.Sprintf("\x00%d", r.ID), r.ID)
+			if err != nil {
+				return fmt.Errorf("failed to rename tag: %w", err)
+			}
+		}
+	}
+
+	for i, r := range renamed {
+		_, err = tx.ExecContext(ctx, "UPDATE tags SET name = ? WHERE id = ?", targets[i], r.ID)
+		if err != nil {
+			if isDuplicateName(err) {
+				return &ExistsError{Name: targets[i], Err: err}
+			}
+
+			return fmt.Errorf("failed to rename tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Merge replaces a tag with another on every bookmark that has it, then
+// deletes it. The tag being merged into is created if it doesn't exist, and
+// takes over the aliases of the merged tag. Merging into an alias merges
+// into the tag it stands for, as tagging a bookmark with it would.
+func (ts *SQLiteTagStore) Merge(ctx context.Context, name string, into string) (*Tag, error) {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	into, err = ts.rules.Normalize(into)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ts.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge tags: %w", err)
+	}
+	defer tx.Rollback()
+
+	resolved, err := ResolveAliases(ctx, tx, Tags{into})
+	if err != nil {
+		return nil, err
+	}
+	into = resolved[0]
+
+	if name == into {
+		return getTag(ctx, tx, name)
+	}
+
+	err = mergeTag(ctx, tx, name, into)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := getTag(ctx, tx, into)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return t, nil
+}
+
+func mergeTag(ctx context.Context, tx *sqlx.Tx, name string, into string) error {
+	from, err := getTag(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+
+	var intoID int64
+	err = tx.GetContext(ctx, &intoID, `
+        INSERT INTO tags (name) VALUES (?)
+        ON CONFLICT (name) DO UPDATE SET name = excluded.name
+        RETURNING id
+    `, into)
+	if err != nil {
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id)
+        SELECT bookmark_id, ? FROM bookmark_tags WHERE tag_id = ?
+    `, intoID, from.ID)
+	if err != nil {
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE tag_aliases SET tag_id = ? WHERE tag_id = ?", intoID, from.ID)
+	if err != nil {
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM tags WHERE id = ?", from.ID)
+	if err != nil {
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return nil
+}
+
+// NormalizeAll applies the tag rules to every existing tag in a single
+// transaction, renaming tags whose names change and merging tags whose
+// normalized names collide. Only tags that changed are reported.
+func (ts *SQLiteTagStore) NormalizeAll(ctx context.Context) ([]*Normalization, error) {
+	tx, err := ts.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize tags: %w", err)
+	}
+	defer tx.Rollback()
+
+	var names []string
+	err = tx.SelectContext(ctx, &names, "SELECT name FROM tags ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("could not select tags: %w", err)
+	}
+
+	results := []*Normalization{}
+	for _, name := range names {
+		n := &Normalization{Name: name}
+
+		n.NewName, n.Err = ts.rules.Normalize(name)
+		if n.Err != nil {
+			results = append(results, n)
+			continue
+		}
+
+		if n.NewName == name {
+			continue
+		}
+
+		// children are visited separately, since they may normalize
+		// differently than their parent
+		err = renameTag(ctx, tx, name, n.NewName, false)
+		if IsAliasExists(err) {
+			// the new name stands for a tag, which this one joins, unless
+			// it's an alias of this very tag
+			var resolved Tags
+			resolved, err = ResolveAliases(ctx, tx, Tags{n.NewName})
+			if err != nil {
+				return nil, err
+			}
+			if resolved[0] == name {
+				n.Err = &InvalidError{Name: name, Reason: fmt.Sprintf("it normalizes to its own alias %s", n.NewName)}
+				results = append(results, n)
+				continue
+			}
+
+			n.NewName = resolved[0]
+			n.Merged = true
+			err = mergeTag(ctx, tx, name, n.NewName)
+		} else if IsExists(err) {
+			n.Merged = true
+			err = mergeTag(ctx, tx, name, n.NewName)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, n)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize tags: %w", err)
+	}
+
+	return results, nil
+}
+
+// Delete removes a tag from every bookmark that has it.
+func (ts *SQLiteTagStore) Delete(ctx context.Context, name string) error {
+	name, err := ts.rules.Normalize(name)
+	if err != nil {
+		return err
+	}
+
+	result, err := ts.db.ExecContext(ctx, "DELETE FROM tags WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag from database: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete tag from database: %w", err)
+	}
+
+	if n == 0 {
+		return &NotFoundError{Name: name}
+	}
+
+	return nil
+}
+
+func isDuplicateName(err error) bool {
+	var sqliteErr *sqlite.Error
+
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteErr.Error(), "tags.name")
+	}
+
+	return false
+}
@@ -29,5 +29,11 @@ func (t *Tags) Scan(src any) error {
 }
 
 func (t Tags) Value() (driver.Value, error) {
-	return json.Marshal(t)
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	// stored as text, since SQLite's JSON functions read blobs as JSONB
+	return string(b), nil
 }
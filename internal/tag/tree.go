@@ -0,0 +1,103 @@
+package tag
+
+import (
+	"slices"
+	"strings"
+)
+
+// Separator divides a tag into a hierarchy, so that "work/infra/k8s" is a
+// child of "work/infra", which is a child of "work".
+const Separator = "/"
+
+// Parent returns the name of the tag directly above name in the hierarchy,
+// or false if name is at the top level.
+func Parent(name string) (string, bool) {
+	i := strings.LastIndex(name, Separator)
+	if i < 0 {
+		return "", false
+	}
+
+	return name[:i], true
+}
+
+// Ancestors returns every tag above name in the hierarchy, starting from the
+// top level.
+func Ancestors(name string) []string {
+	var ancestors []string
+	for p, ok := Parent(name); ok; p, ok = Parent(p) {
+		ancestors = append(ancestors, p)
+	}
+	slices.Reverse(ancestors)
+
+	return ancestors
+}
+
+// IsDescendant reports whether name is beneath ancestor in the hierarchy.
+func IsDescendant(name string, ancestor string) bool {
+	return strings.HasPrefix(name, ancestor+Separator)
+}
+
+// Label returns the last segment of a tag's name.
+func Label(name string) string {
+	return name[strings.LastIndex(name, Separator)+1:]
+}
+
+// Node is a tag in the tag hierarchy. Nodes exist for every ancestor of a
+// tag, even ancestors that aren't themselves used as tags.
+type Node struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Count is the number of bookmarks with exactly this tag.
+	Count int64 `json:"count"`
+	// Total is the number of bookmarks with this tag or any tag beneath it.
+	Total    int64   `json:"total"`
+	Children []*Node `json:"children"`
+}
+
+// BuildTree arranges tags into a hierarchy sorted by name. The totals map
+// gives the number of bookmarks beneath each node, keyed by name.
+func BuildTree(tags []*Tag, totals map[string]int64) []*Node {
+	roots := []*Node{}
+	nodes := map[string]*Node{}
+
+	var node func(name string) *Node
+	node = func(name string) *Node {
+		if n, ok := nodes[name]; ok {
+			return n
+		}
+
+		n := &Node{
+			Name:     name,
+			Label:    Label(name),
+			Total:    totals[name],
+			Children: []*Node{},
+		}
+		nodes[name] = n
+
+		if p, ok := Parent(name); ok {
+			parent := node(p)
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+
+		return n
+	}
+
+	for _, t := range tags {
+		node(t.Name).Count = t.Count
+	}
+
+	sortNodes(roots)
+	return roots
+}
+
+func sortNodes(nodes []*Node) {
+	slices.SortFunc(nodes, func(a, b *Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, n := range nodes {
+		sortNodes(n.Children)
+	}
+}
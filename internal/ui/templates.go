@@ -75,6 +75,12 @@ func NewTemplateRenderer(conf UIConfig) *echo.TemplateRenderer {
 		"formatRelativeTime": func(t time.Time) string {
 			return time.Since(t).String()
 		},
+		"add": func(a, b uint64) uint64 {
+			return a + b
+		},
+		"sub": func(a, b uint64) uint64 {
+			return a - b
+		},
 	}
 
 	maps.Copy(f, conf.TemplateFuncs)
@@ -0,0 +1,227 @@
+// Package urlnorm cleans up bookmark URLs and decides when two URLs point
+// to the same page, so that a page isn't saved twice under slightly
+// different addresses.
+package urlnorm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// TrailingSlash is what to do with a slash at the end of a path.
+type TrailingSlash string
+
+const (
+	// TrailingSlashKeep treats "/a" and "/a/" as different pages.
+	TrailingSlashKeep TrailingSlash = "keep"
+	// TrailingSlashIgnore treats "/a" and "/a/" as the same page, but saves
+	// URLs with whichever was given.
+	TrailingSlashIgnore TrailingSlash = "ignore"
+	// TrailingSlashStrip removes the slash from saved URLs too.
+	TrailingSlashStrip TrailingSlash = "strip"
+)
+
+// Rules control how URLs are cleaned up before they are stored, and which
+// differences between URLs don't count when looking for duplicates. Scheme
+// and host case and default ports never count, and the scheme itself
+// doesn't either, so "http://x.com/a" and "https://x.com/a" are duplicates.
+type Rules struct {
+	// StripParams lists query parameters that are removed from URLs, like
+	// the ones that track where a link was shared. A name ending in "*"
+	// matches every parameter starting with the rest of it.
+	StripParams []string `json:"stripParams"`
+	// TrailingSlash is "keep", "ignore", or "strip".
+	TrailingSlash TrailingSlash `json:"trailingSlash"`
+	// StripFragment removes the part of a URL after "#", except for
+	// fragments starting with "!" or "/", which single-page sites use for
+	// their own paths.
+	StripFragment bool `json:"stripFragment"`
+	// IgnoreWWW treats "www.x.com" and "x.com" as the same site.
+	IgnoreWWW bool `json:"ignoreWWW"`
+}
+
+var DefaultRules = Rules{
+	StripParams: []string{
+		"utm_*",
+		"fbclid",
+		"gclid",
+		"dclid",
+		"gbraid",
+		"wbraid",
+		"msclkid",
+		"yclid",
+		"igshid",
+		"mc_cid",
+		"mc_eid",
+		"_hsenc",
+		"_hsmi",
+		"mkt_tok",
+		"ref_src",
+	},
+	TrailingSlash: TrailingSlashIgnore,
+	StripFragment: true,
+	IgnoreWWW:     true,
+}
+
+func (r *Rules) Validate() error {
+	switch r.TrailingSlash {
+	case TrailingSlashKeep, TrailingSlashIgnore, TrailingSlashStrip:
+		return nil
+	default:
+		return fmt.Errorf("urls.trailingSlash must be one of %s, %s, %s", TrailingSlashKeep, TrailingSlashIgnore, TrailingSlashStrip)
+	}
+}
+
+// Canonicalize cleans up a URL to be stored. Only http and https URLs are
+// changed, and only in ways that lead to the same page. URLs that can't be
+// parsed are returned as they are.
+func (r *Rules) Canonicalize(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !isWeb(u) {
+		return strings.TrimSpace(raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = canonicalHost(u)
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	if r.TrailingSlash == TrailingSlashStrip {
+		trimTrailingSlash(u)
+	}
+
+	u.RawQuery = r.stripParams(u.RawQuery)
+	u.ForceQuery = false
+	if r.StripFragment && !isRoute(u.Fragment) {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	return u.String()
+}
+
+// Key returns what identifies the page at a URL. URLs with the same key
+// are duplicates of each other.
+func (r *Rules) Key(raw string) string {
+	canonical := r.Canonicalize(raw)
+	u, err := url.Parse(canonical)
+	if err != nil || !isWeb(u) {
+		return canonical
+	}
+
+	// the scheme is left out, keeping the leading "//"
+	u.Scheme = ""
+	if r.IgnoreWWW {
+		u.Host = strings.TrimPrefix(u.Host, "www.")
+	}
+	if r.TrailingSlash == TrailingSlashIgnore {
+		trimTrailingSlash(u)
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		slices.Sort(params)
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String()
+}
+
+// Host returns the lower-cased host name of a URL, without a port, or ""
+// if it has none.
+func Host(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+func isWeb(u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	return (scheme == "http" || scheme == "https") && u.Host != ""
+}
+
+// canonicalHost lower-cases the host and removes the port if it's the
+// default one for the scheme, along with the trailing dot of a fully
+// qualified name.
+func canonicalHost(u *url.URL) string {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		return net.JoinHostPort(host, port)
+	}
+
+	if strings.Contains(host, ":") {
+		// an IPv6 address
+		return "[" + host + "]"
+	}
+
+	return host
+}
+
+func trimTrailingSlash(u *url.URL) {
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	if len(u.RawPath) > 1 {
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+}
+
+// stripParams removes the parameters in StripParams from a query, leaving
+// the rest as they were written and in the same order.
+func (r *Rules) stripParams(query string) string {
+	if query == "" || len(r.StripParams) == 0 {
+		return query
+	}
+
+	kept := []string{}
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(param, "=")
+		unescaped, err := url.QueryUnescape(name)
+		if err == nil {
+			name = unescaped
+		}
+
+		if !r.isStripped(name) {
+			kept = append(kept, param)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+func (r *Rules) isStripped(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range r.StripParams {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRoute reports whether a fragment is a path within a single-page site,
+// like "#!/inbox" or "#/settings", rather than a place on the page.
+func isRoute(fragment string) bool {
+	return strings.HasPrefix(fragment, "!") || strings.HasPrefix(fragment, "/")
+}